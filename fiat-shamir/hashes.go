@@ -0,0 +1,181 @@
+package fiatshamir
+
+import (
+	"crypto/sha256"
+	"hash"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
+)
+
+// HashFunc constructs the hash of a transcript. Hashes which also implement io.Reader are extendable-output
+// functions, the challenges are read from them instead of being expanded with a counter.
+type HashFunc func() hash.Hash
+
+// xofHash is a hash with extendable output
+type xofHash interface {
+	hash.Hash
+	io.Reader
+}
+
+const (
+	// xofSize is the length of Sum of the extendable-output functions
+	xofSize = 32
+	// fieldChunk is the number of bytes packed into one element of the scalar field of BN254, 31 bytes are always
+	// smaller than the modulus
+	fieldChunk = 31
+)
+
+// SHA256 returns SHA-256, the hash of the transcripts if no other is given
+func SHA256() hash.Hash {
+	return sha256.New()
+}
+
+// SHA3 returns SHA3-256
+func SHA3() hash.Hash {
+	return sha3.New256()
+}
+
+// SHAKE256 returns SHAKE256 as an extendable-output function, Sum returns 32 bytes
+func SHAKE256() hash.Hash {
+	return &shakeHash{ShakeHash: sha3.NewShake256()}
+}
+
+// BLAKE2b returns BLAKE2b-256
+func BLAKE2b() hash.Hash {
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// BLAKE2s returns BLAKE2s-256
+func BLAKE2s() hash.Hash {
+	h, err := blake2s.New256(nil)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// BLAKE3 returns BLAKE3 as an extendable-output function, Sum returns 32 bytes
+func BLAKE3() hash.Hash {
+	return &blake3Hash{Hasher: blake3.New(xofSize, nil)}
+}
+
+// Poseidon returns Poseidon over the scalar field of BN254 on bytes packed by fieldHash, so that the challenges can
+// be recomputed with the Poseidon of gnark in a circuit
+func Poseidon() hash.Hash {
+	return &fieldHash{Hash: poseidon.NewPoseidon()}
+}
+
+// MiMC returns MiMC over the scalar field of BN254 on bytes packed by fieldHash, so that the challenges can be
+// recomputed with the MiMC of gnark in a circuit
+func MiMC() hash.Hash {
+	return &fieldHash{Hash: mimc.NewMiMC()}
+}
+
+// shakeHash is a sha3.ShakeHash with the methods of hash.Hash
+type shakeHash struct {
+	sha3.ShakeHash
+}
+
+func (h *shakeHash) Sum(b []byte) []byte {
+	ret := make([]byte, xofSize)
+	if _, err := h.Clone().Read(ret); err != nil {
+		panic(err)
+	}
+	return append(b, ret...)
+}
+
+func (h *shakeHash) Size() int {
+	return xofSize
+}
+
+// BlockSize returns the rate of SHAKE256
+func (h *shakeHash) BlockSize() int {
+	return 136
+}
+
+// blake3Hash reads the extendable output of a blake3.Hasher, Write must not be called after Read
+type blake3Hash struct {
+	*blake3.Hasher
+	output *blake3.OutputReader
+}
+
+func (h *blake3Hash) Read(p []byte) (int, error) {
+	if h.output == nil {
+		h.output = h.XOF()
+	}
+	return h.output.Read(p)
+}
+
+func (h *blake3Hash) Reset() {
+	h.Hasher.Reset()
+	h.output = nil
+}
+
+// fieldHash runs a hash over the scalar field of BN254 on bytes packed by fieldElements
+type fieldHash struct {
+	hash.Hash
+	data []byte
+}
+
+func (h *fieldHash) Write(p []byte) (int, error) {
+	h.data = append(h.data, p...)
+	return len(p), nil
+}
+
+func (h *fieldHash) Sum(b []byte) []byte {
+	h.Hash.Reset()
+	for _, element := range fieldElements(h.data) {
+		if _, err := h.Hash.Write(element); err != nil {
+			panic(err)
+		}
+	}
+	return h.Hash.Sum(b)
+}
+
+func (h *fieldHash) Reset() {
+	h.Hash.Reset()
+	h.data = nil
+}
+
+// fieldElements pads data with 0x01 and zeros to a multiple of 31 bytes, and to at least 62 bytes since the Poseidon
+// of gnark needs two inputs, and returns every 31 bytes as one big-endian element of 32 bytes. The padding keeps
+// inputs of different lengths apart.
+func fieldElements(data []byte) [][]byte {
+	padded := append(append([]byte{}, data...), 1)
+	for len(padded)%fieldChunk != 0 || len(padded) < 2*fieldChunk {
+		padded = append(padded, 0)
+	}
+	ret := make([][]byte, 0, len(padded)/fieldChunk)
+	for i := 0; i < len(padded); i += fieldChunk {
+		element := make([]byte, fieldChunk+1)
+		copy(element[1:], padded[i:i+fieldChunk])
+		ret = append(ret, element)
+	}
+	return ret
+}
+
+// readLarge returns the first length bits of the output of xof on input as an integer
+func readLarge(xof xofHash, input []string, length int) *big.Int {
+	for i := range input {
+		if _, err := xof.Write([]byte(input[i])); err != nil {
+			panic(err)
+		}
+	}
+	output := make([]byte, (length+7)/8)
+	if _, err := io.ReadFull(xof, output); err != nil {
+		panic(err)
+	}
+	ret := new(big.Int).SetBytes(output)
+	return ret.Rsh(ret, uint(len(output)*8-length))
+}
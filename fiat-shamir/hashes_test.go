@@ -0,0 +1,207 @@
+package fiatshamir
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/hash/poseidon"
+	"github.com/consensys/gnark/test"
+)
+
+var hashFuncs = []struct {
+	name    string
+	newHash HashFunc
+}{
+	{"SHA256", SHA256},
+	{"SHA3", SHA3},
+	{"SHAKE256", SHAKE256},
+	{"BLAKE2b", BLAKE2b},
+	{"BLAKE2s", BLAKE2s},
+	{"BLAKE3", BLAKE3},
+	{"Poseidon", Poseidon},
+	{"MiMC", MiMC},
+}
+
+func TestHashKnownAnswers(t *testing.T) {
+	// the Poseidon and MiMC answers depend on the packing of fieldElements, TestFieldHashCircuit checks them in a
+	// circuit
+	testCases := []struct {
+		name    string
+		newHash HashFunc
+		input   string
+		want    string
+	}{
+		{"SHA256", SHA256, "", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"SHA256", SHA256, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"SHA3", SHA3, "", "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{"SHA3", SHA3, "abc", "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{"SHAKE256", SHAKE256, "", "46b9dd2b0ba88d13233b3feb743eeb243fcd52ea62b81b82b50c27646ed5762f"},
+		{"SHAKE256", SHAKE256, "abc", "483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739"},
+		{"BLAKE2b", BLAKE2b, "", "0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"},
+		{"BLAKE2b", BLAKE2b, "abc", "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{"BLAKE2s", BLAKE2s, "", "69217a3079908094e11121d042354a7c1f55b6482ca1a51e1b250dfd1ed0eef9"},
+		{"BLAKE2s", BLAKE2s, "abc", "508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982"},
+		{"BLAKE3", BLAKE3, "", "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{"BLAKE3", BLAKE3, "abc", "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+		{"Poseidon", Poseidon, "", "1169b311d4f383c059f151ac5f6024b1e2f822d094195ffa6a01d19bbe8332fa"},
+		{"Poseidon", Poseidon, "abc", "11cadc9ce2fab6c9147399f19a1d686970d126f630b90e3bf6e2ae0d16f02cff"},
+		{"MiMC", MiMC, "", "26b8a1a31ee4efb80b00a2e26b59086368fa4c1af82e9886f072d2be2f8424d0"},
+		{"MiMC", MiMC, "abc", "1e1e8efa005fc714a7fee84bef3b9f9f4107e9002473adfd1d3058c023f849d6"},
+	}
+	for _, tc := range testCases {
+		h := tc.newHash()
+		h.Write([]byte(tc.input))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tc.want {
+			t.Errorf("%s(%q) = %s, want %s", tc.name, tc.input, got, tc.want)
+		}
+		// Sum does not change the state and Reset starts over
+		h.Write([]byte("tail"))
+		h.Reset()
+		h.Write([]byte(tc.input))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tc.want {
+			t.Errorf("%s(%q) after Reset = %s, want %s", tc.name, tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestXOFKnownAnswers(t *testing.T) {
+	want := "483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739" +
+		"d5a15bef186a5386c75744c0527e1faa9f8726e462a12a4feb06bd8801e751e4"
+	h := SHAKE256().(xofHash)
+	h.Write([]byte("abc"))
+	output := make([]byte, 64)
+	h.Read(output)
+	if got := hex.EncodeToString(output); got != want {
+		t.Errorf("SHAKE256(\"abc\") = %s, want %s", got, want)
+	}
+
+	// the output of BLAKE3 extends its hash
+	h = BLAKE3().(xofHash)
+	h.Write([]byte("abc"))
+	sum := h.Sum(nil)
+	h.Read(output)
+	if !bytes.Equal(output[:len(sum)], sum) {
+		t.Errorf("BLAKE3 output does not extend the hash")
+	}
+
+	// large challenges are the leading bits of the output
+	large := HashToLargeWith(SHAKE256, []string{"a", "bc"}, 300)
+	var expected big.Int
+	expected.SetString(want[:76], 16)
+	expected.Rsh(&expected, 4)
+	if large.Cmp(&expected) != 0 {
+		t.Errorf("HashToLargeWith(SHAKE256) does not read the output")
+	}
+}
+
+func TestTranscriptKnownAnswer(t *testing.T) {
+	// the challenges of the proofs depend on the framing, changing it needs a new framingVersion
+	transcript := NewTranscript("VTLP known answer", Max252)
+	transcript.AppendMessage("message", []byte("abc"))
+	want := []string{
+		"445957948515520566487422678333977232416961372512577023096319875517508038",
+		"298636218458854671285651124841669302016058750861981625945437164784720049",
+		"426131195625939174780834794543977086542593116636380918379508685935139344325146831524060260",
+	}
+	got := []*big.Int{transcript.IntChallenge("c"), transcript.PrimeChallenge("l"), transcript.LargeChallenge("gamma", 300)}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("challenge %d is %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTranscriptHashes(t *testing.T) {
+	challenges := make(map[string]string)
+	for _, hf := range hashFuncs {
+		trans1 := NewTranscriptWithHash("test", Max252, hf.newHash)
+		trans1.AppendInt("x", big.NewInt(12))
+		trans2 := NewTranscriptWithHash("test", Max252, hf.newHash)
+		trans2.AppendInt("x", big.NewInt(12))
+		challenge := trans1.IntChallenge("c")
+		if challenge.Cmp(trans2.IntChallenge("c")) != 0 {
+			t.Errorf("%s: same messages lead to different challenges", hf.name)
+		}
+		if other, ok := challenges[challenge.String()]; ok {
+			t.Errorf("%s and %s lead to the same challenge", hf.name, other)
+		}
+		challenges[challenge.String()] = hf.name
+		l := trans1.PrimeChallenge("l")
+		if !l.ProbablyPrime(securityParameter) || l.Cmp(&min253) != -1 {
+			t.Errorf("%s: wrong prime challenge", hf.name)
+		}
+		if l.Cmp(trans2.PrimeChallenge("l")) != 0 {
+			t.Errorf("%s: same messages lead to different prime challenges", hf.name)
+		}
+		if gamma := trans1.LargeChallenge("gamma", 2048+233); gamma.BitLen() > 2048+233 || gamma.BitLen() < 2048 {
+			t.Errorf("%s: wrong bit length for large challenge, length = %d", hf.name, gamma.BitLen())
+		}
+		legacy := InitTranscriptWithHash([]string{"111", "aaa", "333"}, Max252, hf.newHash)
+		if l := legacy.GetPrimeChallengeUsingTranscript(); !l.ProbablyPrime(securityParameter) {
+			t.Errorf("%s: legacy challenge not prime", hf.name)
+		}
+	}
+}
+
+func TestFieldElements(t *testing.T) {
+	for _, length := range []int{0, 1, 30, 31, 61, 62, 63, 100} {
+		elements := fieldElements(bytes.Repeat([]byte{0xff}, length))
+		if len(elements) < 2 || len(elements) != (length+fieldChunk)/fieldChunk && len(elements) != 2 {
+			t.Errorf("%d bytes are packed into %d elements", length, len(elements))
+		}
+		for _, element := range elements {
+			if len(element) != fieldChunk+1 || element[0] != 0 {
+				t.Errorf("%d bytes are packed into an element out of range", length)
+			}
+		}
+	}
+	// inputs differing only in trailing zeros are packed differently
+	if bytes.Equal(bytes.Join(fieldElements([]byte("abc")), nil), bytes.Join(fieldElements([]byte("abc\x00")), nil)) {
+		t.Errorf("the padding does not separate inputs of different lengths")
+	}
+}
+
+// fieldHashCircuit recomputes Poseidon and MiMC of packed elements
+type fieldHashCircuit struct {
+	Elements [3]frontend.Variable
+	Poseidon frontend.Variable `gnark:",public"`
+	MiMC     frontend.Variable `gnark:",public"`
+}
+
+func (circuit *fieldHashCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(poseidon.Poseidon(api, circuit.Elements[:]...), circuit.Poseidon)
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(circuit.Elements[:]...)
+	api.AssertIsEqual(h.Sum(), circuit.MiMC)
+	return nil
+}
+
+func TestFieldHashCircuit(t *testing.T) {
+	input := []byte("the challenges of a transcript can be recomputed in a circuit, three elements")
+	elements := fieldElements(input)
+	if len(elements) != 3 {
+		t.Fatalf("input is packed into %d elements", len(elements))
+	}
+	var witness fieldHashCircuit
+	for i, element := range elements {
+		witness.Elements[i] = new(big.Int).SetBytes(element)
+	}
+	h := Poseidon()
+	h.Write(input)
+	witness.Poseidon = new(big.Int).SetBytes(h.Sum(nil))
+	h = MiMC()
+	h.Write(input)
+	witness.MiMC = new(big.Int).SetBytes(h.Sum(nil))
+	if err := test.IsSolved(&fieldHashCircuit{}, &witness, ecc.BN254, backend.GROTH16); err != nil {
+		t.Errorf("the circuit does not recompute the hashes: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package fiatshamir
+
+import (
+	"encoding/binary"
+	"io"
+	"math/big"
+)
+
+// framingVersion starts every transcript of NewTranscript, it separates the framed transcripts from the string
+// transcripts of InitTranscript and from later versions of the framing
+const framingVersion = "VTLP transcript v2"
+
+// intChallengeBytes is the number of bytes of an integer challenge before it is wrapped to the challenge length
+const intChallengeBytes = 32
+
+// NewTranscript returns a transcript in the style of Merlin for the protocol: every message is framed with its label
+// and length, and the protocol name is appended as a domain separator before anything else. Such a transcript must
+// only be extended with AppendMessage, AppendInt and the challenges below, mixing in Append and AppendSlice gives up
+// the framing.
+func NewTranscript(protocol string, length ChallengeLength) *Transcript {
+	return NewTranscriptWithHash(protocol, length, SHA256)
+}
+
+// NewTranscriptWithHash returns a transcript like NewTranscript whose challenges are computed with newHash
+func NewTranscriptWithHash(protocol string, length ChallengeLength, newHash HashFunc) *Transcript {
+	ret := &Transcript{maxlength: length, info: []string{framingVersion}, hash: newHash}
+	ret.AppendMessage("dom-sep", []byte(protocol))
+	return ret
+}
+
+// Fork returns a child transcript for a sub-protocol, it holds everything appended to transcript so far followed by
+// label, so that the challenges of the child are bound to the context of the parent. The children of one state need
+// different labels, and neither the child nor the parent sees what is appended to the other afterwards.
+func (transcript *Transcript) Fork(label string) *Transcript {
+	ret := &Transcript{maxlength: transcript.maxlength, hash: transcript.hash}
+	ret.info = append(make([]string, 0, len(transcript.info)+1), transcript.info...)
+	ret.AppendMessage("fork", []byte(label))
+	return ret
+}
+
+// AppendMessage appends message under label, both are prefixed with their length as 4 big-endian bytes, so that no
+// two different sequences of messages give the same transcript
+func (transcript *Transcript) AppendMessage(label string, message []byte) {
+	record := make([]byte, 0, 8+len(label)+len(message))
+	record = binary.BigEndian.AppendUint32(record, uint32(len(label)))
+	record = append(record, label...)
+	record = binary.BigEndian.AppendUint32(record, uint32(len(message)))
+	record = append(record, message...)
+	transcript.info = append(transcript.info, string(record))
+}
+
+// AppendInt appends x under label as a sign byte, 1 for negative numbers and 0 otherwise, followed by the minimal
+// big-endian bytes of |x|
+func (transcript *Transcript) AppendInt(label string, x *big.Int) {
+	var sign byte
+	if x.Sign() < 0 {
+		sign = 1
+	}
+	transcript.AppendMessage(label, append([]byte{sign}, x.Bytes()...))
+}
+
+// AppendInts appends every x under label
+func (transcript *Transcript) AppendInts(label string, xs ...*big.Int) {
+	for _, x := range xs {
+		transcript.AppendInt(label, x)
+	}
+}
+
+// ChallengeBytes returns n bytes bound to everything appended so far. The label and n are appended first, so that
+// every challenge differs from the previous ones. The bytes are read from the output of an extendable-output
+// function, and otherwise are the hashes of the transcript and a 4 byte counter, repeated until n bytes are produced.
+func (transcript *Transcript) ChallengeBytes(label string, n int) []byte {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(n))
+	transcript.AppendMessage(label, length[:])
+
+	h := transcript.hashFunc()()
+	if xof, ok := h.(xofHash); ok {
+		for i := range transcript.info {
+			xof.Write([]byte(transcript.info[i]))
+		}
+		ret := make([]byte, n)
+		if _, err := io.ReadFull(xof, ret); err != nil {
+			panic(err)
+		}
+		return ret
+	}
+	ret := make([]byte, 0, n+h.Size())
+	for counter := uint32(0); len(ret) < n; counter++ {
+		h.Reset()
+		for i := range transcript.info {
+			h.Write([]byte(transcript.info[i]))
+		}
+		var block [4]byte
+		binary.BigEndian.PutUint32(block[:], counter)
+		h.Write(block[:])
+		ret = h.Sum(ret)
+	}
+	return ret[:n]
+}
+
+// IntChallenge returns an integer challenge under label with the length of the transcript
+func (transcript *Transcript) IntChallenge(label string) *big.Int {
+	return wrapNumber(transcript.ChallengeBytes(label, intChallengeBytes), transcript.maxlength)
+}
+
+// PrimeChallenge returns a prime challenge under label with the length of the transcript, it is the first prime of
+// HashToPrimeNonce on a seed drawn under label. Prover and verifier both run the search.
+func (transcript *Transcript) PrimeChallenge(label string) *big.Int {
+	ret, _ := HashToPrimeNonceWith(transcript.hashFunc(), transcript.primeSeed(label), transcript.maxlength)
+	return ret
+}
+
+// primeSeed draws the seed of a prime challenge under label
+func (transcript *Transcript) primeSeed(label string) []string {
+	return []string{string(transcript.ChallengeBytes(label, intChallengeBytes))}
+}
+
+// LargeChallenge returns a challenge under label in [0, 2^bits)
+func (transcript *Transcript) LargeChallenge(label string, bits int) *big.Int {
+	ret := new(big.Int).SetBytes(transcript.ChallengeBytes(label, (bits+7)/8))
+	return ret.Rsh(ret, uint((8-bits%8)%8))
+}
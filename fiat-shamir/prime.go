@@ -0,0 +1,203 @@
+package fiatshamir
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// MaxPrimeNonce bounds the nonces of HashToPrimeNonce and HashToCertifiedPrime. About one candidate in 85 is prime so
+// that the search fails with probability below 2^-1000. The verifiers check that the candidates of the smaller nonces
+// are composite, so a prover can not pick another prime than the first one, which makes verifying a nonce as costly
+// as the search.
+const MaxPrimeNonce = 1 << 16
+
+var (
+	// pocklingtonPrimes are the prime factors of pocklingtonF, 2^64-59 and 2^64-83 are prime
+	pocklingtonPrimes = []*big.Int{big.NewInt(2), bigFromString("18446744073709551557"), bigFromString("18446744073709551533")}
+	// pocklingtonF is the known part of p-1 of a certified prime p = F*h + 1, it has 129 bits
+	pocklingtonF = new(big.Int).Mul(pocklingtonPrimes[0], new(big.Int).Mul(pocklingtonPrimes[1], pocklingtonPrimes[2]))
+
+	big1 = big.NewInt(1)
+
+	errCertifiedLength = errors.New("challenge length does not fit a certified prime")
+	errNoPrime         = errors.New("no prime below MaxPrimeNonce")
+)
+
+// minCofactorBits is the least length of the hashed cofactor h of a certified prime
+const minCofactorBits = 64
+
+func bigFromString(s string) *big.Int {
+	ret, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid integer " + s)
+	}
+	return ret
+}
+
+// PrimeCertificate proves that the candidate of Nonce is prime, Witness is the base of the Pocklington test
+type PrimeCertificate struct {
+	Nonce   uint32
+	Witness uint32
+}
+
+// challengeBits returns the number of bits of the challenges of length
+func challengeBits(length ChallengeLength) int {
+	if length == Max252 {
+		return bitLimit - 1
+	}
+	return int(length)
+}
+
+// nonceHash returns the hash of the input followed by nonce as 4 big-endian bytes
+func nonceHash(newHash HashFunc, input []string, nonce uint32) []byte {
+	h := newHash()
+	for i := range input {
+		if _, err := h.Write([]byte(input[i])); err != nil {
+			panic(err)
+		}
+	}
+	var counter [4]byte
+	binary.BigEndian.PutUint32(counter[:], nonce)
+	h.Write(counter[:])
+	return h.Sum(nil)
+}
+
+// primeCandidate returns the odd candidate of nonce
+func primeCandidate(newHash HashFunc, input []string, length ChallengeLength, nonce uint32) *big.Int {
+	ret := wrapNumber(nonceHash(newHash, input, nonce), length)
+	return ret.SetBit(ret, 0, 1)
+}
+
+// HashToPrimeNonce returns the first probable prime among the hashes of the input followed by a counter, and the counter
+// as the nonce. VerifyHashToPrime tests every candidate up to the nonce, so the nonce saves the verifier no work, it
+// only records which candidate was taken.
+func HashToPrimeNonce(input []string, length ChallengeLength) (*big.Int, uint32) {
+	return HashToPrimeNonceWith(SHA256, input, length)
+}
+
+// HashToPrimeNonceWith is HashToPrimeNonce with the hash of newHash
+func HashToPrimeNonceWith(newHash HashFunc, input []string, length ChallengeLength) (*big.Int, uint32) {
+	for nonce := uint32(0); nonce < MaxPrimeNonce; nonce++ {
+		ret := primeCandidate(newHash, input, length, nonce)
+		if ret.ProbablyPrime(securityParameter) {
+			return ret, nonce
+		}
+	}
+	panic(errNoPrime)
+}
+
+// VerifyHashToPrime returns the candidate of nonce and whether it is the first probable prime. The candidates of the
+// smaller nonces are tested too, so this costs as much as HashToPrimeNonce.
+func VerifyHashToPrime(input []string, length ChallengeLength, nonce uint32) (*big.Int, bool) {
+	return VerifyHashToPrimeWith(SHA256, input, length, nonce)
+}
+
+// VerifyHashToPrimeWith is VerifyHashToPrime with the hash of newHash
+func VerifyHashToPrimeWith(newHash HashFunc, input []string, length ChallengeLength, nonce uint32) (*big.Int, bool) {
+	if nonce >= MaxPrimeNonce {
+		return nil, false
+	}
+	ret := primeCandidate(newHash, input, length, nonce)
+	for other := uint32(0); other < nonce; other++ {
+		if primeCandidate(newHash, input, length, other).ProbablyPrime(securityParameter) {
+			return ret, false
+		}
+	}
+	return ret, ret.ProbablyPrime(securityParameter)
+}
+
+// certifiedCandidate returns the candidate p = F*h + 1 of nonce where h is the leading bits of the hash, so that p has
+// at most the bits of length, h < F keeps F above the square root of p
+func certifiedCandidate(newHash HashFunc, input []string, length ChallengeLength, nonce uint32) (*big.Int, error) {
+	bits := challengeBits(length) - pocklingtonF.BitLen()
+	sum := nonceHash(newHash, input, nonce)
+	if bits < minCofactorBits || bits >= pocklingtonF.BitLen() || bits > len(sum)*8 {
+		return nil, errCertifiedLength
+	}
+	ret := new(big.Int).SetBytes(sum)
+	ret.Rsh(ret, uint(len(sum)*8-bits))
+	ret.Mul(ret, pocklingtonF)
+	return ret.Add(ret, big1), nil
+}
+
+// pocklington checks the Pocklington criterion for p = F*h + 1 with base a: a^((p-1)/2) = -1 mod p, which implies
+// a^(p-1) = 1, and gcd(a^((p-1)/q) - 1, p) = 1 for the odd prime factors q of F. Since F > sqrt(p) this proves that
+// p is prime.
+func pocklington(p *big.Int, a uint32) bool {
+	var base, pm1, exp, power, gcd big.Int
+	base.SetUint64(uint64(a))
+	pm1.Sub(p, big1)
+	if a < 2 || base.Cmp(&pm1) >= 0 {
+		return false
+	}
+	for _, q := range pocklingtonPrimes {
+		exp.Quo(&pm1, q)
+		power.Exp(&base, &exp, p)
+		if q.Cmp(pocklingtonPrimes[0]) == 0 {
+			if power.Cmp(&pm1) != 0 {
+				return false
+			}
+			continue
+		}
+		power.Sub(&power, big1)
+		if gcd.GCD(nil, nil, &power, p).Cmp(big1) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// HashToCertifiedPrime returns a prime of the form F*h + 1 where h is hashed from the input and a counter, and a
+// certificate which proves its primality with three modular exponentiations. The length must leave room for h, as
+// Max252 does. The verifier still tests the candidates of the smaller nonces, the certificate only replaces the
+// probable prime test of the last one.
+func HashToCertifiedPrime(input []string, length ChallengeLength) (*big.Int, *PrimeCertificate, error) {
+	return HashToCertifiedPrimeWith(SHA256, input, length)
+}
+
+// HashToCertifiedPrimeWith is HashToCertifiedPrime with the hash of newHash
+func HashToCertifiedPrimeWith(newHash HashFunc, input []string, length ChallengeLength) (*big.Int, *PrimeCertificate, error) {
+	for nonce := uint32(0); nonce < MaxPrimeNonce; nonce++ {
+		ret, err := certifiedCandidate(newHash, input, length, nonce)
+		if err != nil {
+			return nil, nil, err
+		}
+		// composites have no witness, the probable prime test skips them before the search
+		if !ret.ProbablyPrime(0) {
+			continue
+		}
+		for a := uint32(2); a < 1<<10; a++ {
+			if pocklington(ret, a) {
+				return ret, &PrimeCertificate{Nonce: nonce, Witness: a}, nil
+			}
+		}
+		// the verifier rejects a later nonce after a prime candidate, about half of the bases are witnesses
+		return nil, nil, errNoPrime
+	}
+	return nil, nil, errNoPrime
+}
+
+// VerifyCertifiedPrime returns the candidate of the certificate and whether the certificate proves it to be the first
+// prime, the candidates of the smaller nonces have to fail the probable prime test. This costs about as much as
+// HashToCertifiedPrime.
+func VerifyCertifiedPrime(input []string, length ChallengeLength, cert *PrimeCertificate) (*big.Int, bool) {
+	return VerifyCertifiedPrimeWith(SHA256, input, length, cert)
+}
+
+// VerifyCertifiedPrimeWith is VerifyCertifiedPrime with the hash of newHash
+func VerifyCertifiedPrimeWith(newHash HashFunc, input []string, length ChallengeLength, cert *PrimeCertificate) (*big.Int, bool) {
+	if cert == nil || cert.Nonce >= MaxPrimeNonce {
+		return nil, false
+	}
+	ret, err := certifiedCandidate(newHash, input, length, cert.Nonce)
+	if err != nil {
+		return nil, false
+	}
+	for nonce := uint32(0); nonce < cert.Nonce; nonce++ {
+		if other, _ := certifiedCandidate(newHash, input, length, nonce); other.ProbablyPrime(0) {
+			return ret, false
+		}
+	}
+	return ret, pocklington(ret, cert.Witness)
+}
@@ -0,0 +1,170 @@
+package fiatshamir
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+func TestPocklingtonPrimes(t *testing.T) {
+	for _, q := range pocklingtonPrimes {
+		if !q.ProbablyPrime(0) {
+			t.Errorf("%s is not prime", q)
+		}
+	}
+	if pocklingtonF.BitLen() != 129 {
+		t.Errorf("F has %d bits", pocklingtonF.BitLen())
+	}
+}
+
+func TestHashToPrimeNonce(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		input := []string{"111", "aaa", strconv.Itoa(i)}
+		prime, nonce := HashToPrimeNonce(input, Max252)
+		if !prime.ProbablyPrime(securityParameter) || prime.Cmp(&min253) != -1 {
+			t.Errorf("wrong prime %s", prime)
+		}
+		verified, ok := VerifyHashToPrime(input, Max252, nonce)
+		if !ok || verified.Cmp(prime) != 0 {
+			t.Errorf("nonce %d is not verified", nonce)
+		}
+		// the earlier candidates are composite
+		for other := uint32(0); other < nonce; other++ {
+			if _, ok := VerifyHashToPrime(input, Max252, other); ok {
+				t.Errorf("nonce %d is not the first prime", nonce)
+			}
+		}
+	}
+	// a later prime is rejected, the prover can not choose among the primes
+	input := []string{"111", "aaa", "333"}
+	_, nonce := HashToPrimeNonce(input, Max252)
+	later := nonce + 1
+	for !primeCandidate(SHA256, input, Max252, later).ProbablyPrime(securityParameter) {
+		later++
+	}
+	if _, ok := VerifyHashToPrime(input, Max252, later); ok {
+		t.Errorf("nonce %d of a later prime is verified", later)
+	}
+	if _, ok := VerifyHashToPrime([]string{"111"}, Max252, MaxPrimeNonce); ok {
+		t.Errorf("nonce above MaxPrimeNonce is verified")
+	}
+
+	// the hash is part of the candidate
+	prime, nonce := HashToPrimeNonceWith(SHA3, []string{"111"}, Max252)
+	if verified, ok := VerifyHashToPrimeWith(SHA3, []string{"111"}, Max252, nonce); !ok || verified.Cmp(prime) != 0 {
+		t.Errorf("nonce %d is not verified with SHA3", nonce)
+	}
+	if verified, _ := VerifyHashToPrime([]string{"111"}, Max252, nonce); verified.Cmp(prime) == 0 {
+		t.Errorf("SHA256 and SHA3 lead to the same candidate")
+	}
+}
+
+func TestHashToCertifiedPrime(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		input := []string{"111", "aaa", strconv.Itoa(i)}
+		prime, cert, err := HashToCertifiedPrime(input, Max252)
+		if err != nil {
+			t.Fatalf("error not empty for HashToCertifiedPrime: %v", err)
+		}
+		if !prime.ProbablyPrime(securityParameter) || prime.Cmp(&min253) != -1 {
+			t.Errorf("wrong prime %s", prime)
+		}
+		var h, r big.Int
+		h.QuoRem(new(big.Int).Sub(prime, big1), pocklingtonF, &r)
+		if r.Sign() != 0 || h.Cmp(pocklingtonF) != -1 {
+			t.Errorf("prime %s is not of the form F*h + 1 with h < F", prime)
+		}
+		verified, ok := VerifyCertifiedPrime(input, Max252, cert)
+		if !ok || verified.Cmp(prime) != 0 {
+			t.Errorf("certificate %v is not verified", cert)
+		}
+		if _, ok := VerifyCertifiedPrime([]string{"other"}, Max252, cert); ok {
+			t.Errorf("certificate %v is verified for another input", cert)
+		}
+	}
+
+	// composite candidates and wrong witnesses are rejected
+	input := []string{"111", "aaa", "333"}
+	_, cert, err := HashToCertifiedPrime(input, Max252)
+	if err != nil {
+		t.Fatalf("error not empty for HashToCertifiedPrime: %v", err)
+	}
+	for nonce := uint32(0); nonce < cert.Nonce; nonce++ {
+		for a := uint32(0); a < 50; a++ {
+			if _, ok := VerifyCertifiedPrime(input, Max252, &PrimeCertificate{Nonce: nonce, Witness: a}); ok {
+				t.Errorf("composite candidate of nonce %d is certified by %d", nonce, a)
+			}
+		}
+	}
+	// a later prime is rejected even with a valid witness
+	later := PrimeCertificate{Nonce: cert.Nonce + 1}
+	for {
+		candidate, _ := certifiedCandidate(SHA256, input, Max252, later.Nonce)
+		if candidate.ProbablyPrime(0) {
+			later.Witness = 2
+			for !pocklington(candidate, later.Witness) {
+				later.Witness++
+			}
+			break
+		}
+		later.Nonce++
+	}
+	if _, ok := VerifyCertifiedPrime(input, Max252, &later); ok {
+		t.Errorf("certificate %v of a later prime is verified", later)
+	}
+	// 4 is a square and can not be a witness
+	if _, ok := VerifyCertifiedPrime(input, Max252, &PrimeCertificate{Nonce: cert.Nonce, Witness: 4}); ok {
+		t.Errorf("a square is a witness")
+	}
+	if _, ok := VerifyCertifiedPrime(input, Max252, nil); ok {
+		t.Errorf("missing certificate is verified")
+	}
+
+	// the length must leave room for the hashed part
+	if _, _, err := HashToCertifiedPrime(input, Default); err == nil {
+		t.Errorf("certified prime of the default length")
+	}
+	if _, _, err := HashToCertifiedPrime(input, 512); err == nil {
+		t.Errorf("certified prime without F > sqrt(p)")
+	}
+}
+
+func BenchmarkHashToPrime(b *testing.B) {
+	inputs := make([][]string, 64)
+	for i := range inputs {
+		inputs[i] = []string{"benchmark", strconv.Itoa(i)}
+	}
+	nonces := make([]uint32, len(inputs))
+	certs := make([]*PrimeCertificate, len(inputs))
+	for i, input := range inputs {
+		_, nonces[i] = HashToPrimeNonce(input, Max252)
+		_, certs[i], _ = HashToCertifiedPrime(input, Max252)
+	}
+
+	// the verifier of HashToPrime repeats the search of the prover
+	b.Run("search", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			HashToPrime(inputs[i%len(inputs)], Max252)
+		}
+	})
+	b.Run("nonce/prove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			HashToPrimeNonce(inputs[i%len(inputs)], Max252)
+		}
+	})
+	b.Run("nonce/verify", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			VerifyHashToPrime(inputs[i%len(inputs)], Max252, nonces[i%len(inputs)])
+		}
+	})
+	b.Run("certified/prove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			HashToCertifiedPrime(inputs[i%len(inputs)], Max252)
+		}
+	})
+	b.Run("certified/verify", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			VerifyCertifiedPrime(inputs[i%len(inputs)], Max252, certs[i%len(inputs)])
+		}
+	})
+}
@@ -1,7 +1,6 @@
 package fiatshamir
 
 import (
-	"crypto/sha256"
 	"fmt"
 	"math/big"
 )
@@ -31,6 +30,8 @@ const (
 type Transcript struct {
 	info      []string
 	maxlength ChallengeLength
+	// hash is the hash of the challenges, SHA-256 if nil
+	hash HashFunc
 }
 
 // Print outputs the info in the transcript
@@ -50,6 +51,21 @@ func InitTranscript(input []string, length ChallengeLength) *Transcript {
 	return &ret
 }
 
+// InitTranscriptWithHash inits a transcript with the input strings whose challenges are computed with newHash
+func InitTranscriptWithHash(input []string, length ChallengeLength, newHash HashFunc) *Transcript {
+	ret := InitTranscript(input, length)
+	ret.hash = newHash
+	return ret
+}
+
+// hashFunc returns the hash of the challenges
+func (transcript *Transcript) hashFunc() HashFunc {
+	if transcript.hash == nil {
+		return SHA256
+	}
+	return transcript.hash
+}
+
 // Append add new info into the transcript
 func (transcript *Transcript) Append(newInfo string) {
 	transcript.info = append(transcript.info, newInfo)
@@ -63,7 +79,7 @@ func (transcript *Transcript) AppendSlice(newInfo []string) {
 // GetPrimeChallengeUsingTranscript returns a challenge and appends the challenge as part of the transcript
 func (transcript *Transcript) GetPrimeChallengeUsingTranscript() *big.Int {
 	var ret big.Int
-	ret.Set(HashToPrime(transcript.info, transcript.maxlength))
+	ret.Set(HashToPrimeWith(transcript.hashFunc(), transcript.info, transcript.maxlength))
 	transcript.Append(ret.String())
 	return &ret
 }
@@ -71,7 +87,7 @@ func (transcript *Transcript) GetPrimeChallengeUsingTranscript() *big.Int {
 // GetIntChallengeUsingTranscript returns a challenge and appends the challenge as part of the transcript
 func (transcript *Transcript) GetIntChallengeUsingTranscript() *big.Int {
 	var ret big.Int
-	ret.Set(HashToInt(transcript.info, transcript.maxlength))
+	ret.Set(HashToIntWith(transcript.hashFunc(), transcript.info, transcript.maxlength))
 	transcript.Append(ret.String())
 	return &ret
 }
@@ -79,7 +95,7 @@ func (transcript *Transcript) GetIntChallengeUsingTranscript() *big.Int {
 // GetLargeChallengeUsingTranscript returns a challenge and appends the challenge as part of the transcript
 func (transcript *Transcript) GetLargeChallengeUsingTranscript(length int) *big.Int {
 	var ret big.Int
-	ret.Set(HashToLarge(transcript.info, length))
+	ret.Set(HashToLargeWith(transcript.hashFunc(), transcript.info, length))
 	transcript.Append(ret.String())
 	return &ret
 }
@@ -105,7 +121,12 @@ func wrapNumber(input []byte, length ChallengeLength) *big.Int {
 // HashToPrime takes the input into Sha256 and take the hash output to input repeatedly until we hit a prime number
 // length of challenge is based on the input length. Default is 256-bit.
 func HashToPrime(input []string, length ChallengeLength) *big.Int {
-	h := sha256.New()
+	return HashToPrimeWith(SHA256, input, length)
+}
+
+// HashToPrimeWith is HashToPrime with the hash of newHash
+func HashToPrimeWith(newHash HashFunc, input []string, length ChallengeLength) *big.Int {
+	h := newHash()
 	for i := 0; i < len(input); i++ {
 		_, err := h.Write([]byte(input[i]))
 		if err != nil {
@@ -133,7 +154,12 @@ func HashToPrime(input []string, length ChallengeLength) *big.Int {
 // HashToInt takes the input into Sha256 and take the hash output as an integer
 // length of challenge is based on the input length. Default is 256-bit.
 func HashToInt(input []string, length ChallengeLength) *big.Int {
-	h := sha256.New()
+	return HashToIntWith(SHA256, input, length)
+}
+
+// HashToIntWith is HashToInt with the hash of newHash
+func HashToIntWith(newHash HashFunc, input []string, length ChallengeLength) *big.Int {
+	h := newHash()
 	for i := 0; i < len(input); i++ {
 		_, err := h.Write([]byte(input[i]))
 		if err != nil {
@@ -148,15 +174,24 @@ func HashToInt(input []string, length ChallengeLength) *big.Int {
 // HashTolarge takes the input into Sha256 and take the hash output as an integer
 // length of challenge is based on the input length.
 func HashToLarge(input []string, length int) *big.Int {
+	return HashToLargeWith(SHA256, input, length)
+}
+
+// HashToLargeWith is HashToLarge with the hash of newHash, the challenge is read directly from extendable-output
+// functions
+func HashToLargeWith(newHash HashFunc, input []string, length int) *big.Int {
+	if xof, ok := newHash().(xofHash); ok {
+		return readLarge(xof, input, length)
+	}
 	if length <= 256 {
-		HashToInt(input, ChallengeLength(length))
+		HashToIntWith(newHash, input, ChallengeLength(length))
 	}
 	var rounds, reminder int
 	rounds = length / 256
 	reminder = length - 256*rounds
 	if reminder != 0 {
 		tempBig := make([]big.Int, rounds+1)
-		h := sha256.New()
+		h := newHash()
 		for i := 0; i < len(input); i++ {
 			_, err := h.Write([]byte(input[i]))
 			if err != nil {
@@ -166,7 +201,7 @@ func HashToLarge(input []string, length int) *big.Int {
 		hashTemp := h.Sum(nil)
 		tempBig[0].SetBytes(hashTemp)
 		for i := 1; i < rounds; i++ {
-			h := sha256.New()
+			h := newHash()
 			_, err := h.Write([]byte(tempBig[i-1].String()))
 			if err != nil {
 				panic(err)
@@ -174,7 +209,7 @@ func HashToLarge(input []string, length int) *big.Int {
 			hashTemp := h.Sum(nil)
 			tempBig[i].SetBytes(hashTemp)
 		}
-		tempBig[rounds].Set(HashToInt(input, ChallengeLength(reminder)))
+		tempBig[rounds].Set(HashToIntWith(newHash, input, ChallengeLength(reminder)))
 		var ret big.Int
 		ret.Set(&tempBig[0])
 		for i := 1; i < rounds; i++ {
@@ -187,7 +222,7 @@ func HashToLarge(input []string, length int) *big.Int {
 	}
 
 	tempBig := make([]big.Int, rounds)
-	h := sha256.New()
+	h := newHash()
 	for i := 0; i < len(input); i++ {
 		_, err := h.Write([]byte(input[i]))
 		if err != nil {
@@ -197,7 +232,7 @@ func HashToLarge(input []string, length int) *big.Int {
 	hashTemp := h.Sum(nil)
 	tempBig[0].SetBytes(hashTemp)
 	for i := 1; i < rounds; i++ {
-		h := sha256.New()
+		h := newHash()
 		_, err := h.Write([]byte(tempBig[i-1].String()))
 		if err != nil {
 			panic(err)
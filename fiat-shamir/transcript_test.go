@@ -1,6 +1,7 @@
 package fiatshamir
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
@@ -135,3 +136,103 @@ func FuzzChallenge(f *testing.F) {
 		}
 	})
 }
+
+func TestMessageTranscript(t *testing.T) {
+	// the string transcript hashes ["12", "3"] and ["1", "23"] to the same challenge, the framed one must not
+	trans1 := NewTranscript("test", Max252)
+	trans1.AppendMessage("x", []byte("12"))
+	trans1.AppendMessage("x", []byte("3"))
+	trans2 := NewTranscript("test", Max252)
+	trans2.AppendMessage("x", []byte("1"))
+	trans2.AppendMessage("x", []byte("23"))
+	if trans1.IntChallenge("c").Cmp(trans2.IntChallenge("c")) == 0 {
+		t.Errorf("Different messages lead to the same challenge")
+	}
+
+	// the labels and the protocol are part of the transcript
+	trans1 = NewTranscript("test", Max252)
+	trans1.AppendMessage("x", []byte("12"))
+	trans2 = NewTranscript("test", Max252)
+	trans2.AppendMessage("y", []byte("12"))
+	trans3 := NewTranscript("other", Max252)
+	trans3.AppendMessage("x", []byte("12"))
+	challenge1 := trans1.IntChallenge("c")
+	if challenge1.Cmp(trans2.IntChallenge("c")) == 0 || challenge1.Cmp(trans3.IntChallenge("c")) == 0 {
+		t.Errorf("Different labels or protocols lead to the same challenge")
+	}
+
+	// the same messages lead to the same challenges, later challenges differ from earlier ones
+	trans2 = NewTranscript("test", Max252)
+	trans2.AppendMessage("x", []byte("12"))
+	if challenge1.Cmp(trans2.IntChallenge("c")) != 0 {
+		t.Errorf("Same messages lead to different challenges")
+	}
+	if challenge1.Cmp(trans1.IntChallenge("c")) == 0 {
+		t.Errorf("Updated transcript has old results")
+	}
+
+	// the sign of an integer is part of its message
+	trans1 = NewTranscript("test", Max252)
+	trans1.AppendInt("x", big.NewInt(5))
+	trans2 = NewTranscript("test", Max252)
+	trans2.AppendInt("x", big.NewInt(-5))
+	if trans1.IntChallenge("c").Cmp(trans2.IntChallenge("c")) == 0 {
+		t.Errorf("Integers of different signs lead to the same challenge")
+	}
+}
+
+func TestMessageChallengeLength(t *testing.T) {
+	trans1 := NewTranscript("test", Max252)
+	for i := 0; i < 10; i++ {
+		challenge := trans1.PrimeChallenge("l")
+		if !challenge.ProbablyPrime(securityParameter) {
+			t.Errorf("Challenge not prime")
+		}
+		if challenge.Cmp(&min253) != -1 {
+			t.Errorf("Challenge larger than min253")
+		}
+	}
+	for _, length := range []int{1, 7, 8, 255, 256, 257, 2048 + 233} {
+		if challenge := trans1.LargeChallenge("gamma", length); challenge.BitLen() > length {
+			t.Error("Wrong bit length for large challenge, length = ", challenge.BitLen())
+		}
+		if bytes := trans1.ChallengeBytes("bytes", length); len(bytes) != length {
+			t.Error("Wrong number of challenge bytes, length = ", len(bytes))
+		}
+	}
+}
+
+func TestFork(t *testing.T) {
+	parent := NewTranscript("test", Max252)
+	parent.AppendMessage("x", []byte("statement"))
+	child1 := parent.Fork("pi1")
+	child2 := parent.Fork("pi2")
+	again := parent.Fork("pi1")
+
+	challenge1 := child1.IntChallenge("c")
+	if challenge1.Cmp(child2.IntChallenge("c")) == 0 {
+		t.Errorf("Children with different labels lead to the same challenge")
+	}
+	if challenge1.Cmp(again.IntChallenge("c")) != 0 {
+		t.Errorf("Children with the same label lead to different challenges")
+	}
+
+	// the child is bound to the parent, and the parent does not see the child
+	other := NewTranscript("test", Max252)
+	other.AppendMessage("x", []byte("another statement"))
+	if challenge1.Cmp(other.Fork("pi1").IntChallenge("c")) == 0 {
+		t.Errorf("Children of different parents lead to the same challenge")
+	}
+	fresh := NewTranscript("test", Max252)
+	fresh.AppendMessage("x", []byte("statement"))
+	if parent.IntChallenge("c").Cmp(fresh.IntChallenge("c")) != 0 {
+		t.Errorf("Forking changes the parent")
+	}
+
+	// a child keeps the hash of the parent
+	parent = NewTranscriptWithHash("test", Max252, SHA3)
+	fresh = NewTranscript("test", Max252)
+	if parent.Fork("pi1").IntChallenge("c").Cmp(fresh.Fork("pi1").IntChallenge("c")) == 0 {
+		t.Errorf("Child does not use the hash of the parent")
+	}
+}
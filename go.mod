@@ -1,21 +1,27 @@
 module github.com/VTLP
 
-go 1.19
+go 1.20
 
 require (
 	github.com/consensys/gnark v0.7.0
 	github.com/consensys/gnark-crypto v0.10.0
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/remyoudompheng/bigfft v0.0.0-20220927061507-ef77025ab5aa
+	golang.org/x/crypto v0.1.0
+	lukechampine.com/blake3 v1.1.7
 )
 
 require (
-	github.com/fxamacker/cbor/v2 v2.4.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
+	github.com/kr/text v0.2.0 // indirect
 	github.com/mmcloughlin/addchain v0.4.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rs/zerolog v1.26.1 // indirect
 	github.com/stretchr/testify v1.8.2 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
-	golang.org/x/crypto v0.1.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace (
@@ -1,8 +1,11 @@
 package protocol
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"io"
 	"math/big"
+	"sync"
 )
 
 // RSAExpProof contains the public/private parts of an RSA key, RSAMod = P*Q, D=publicKey(default 17), D*E = 1 mod phi(N)
@@ -16,16 +19,47 @@ type RSAExpProof struct {
 	//Order is the order of subgroup QR_N
 	Order    *big.Int
 	Exponent *big.Int // Exponent  = 2^TimePara mod Order
+
+	lock sync.Mutex
+	// exponents caches 2^T mod Order for every T used with this setup
+	exponents map[int64]*big.Int
 }
 
+// RSAExpSetup returns the RSA key from the hardcoded test primes Pstring and Qstring, DO NOT use in production
 func RSAExpSetup() *RSAExpProof {
+	var P, Q big.Int
+	P.SetString(Pstring, 10)
+	Q.SetString(Qstring, 10)
+	return newRSAExpProof(&P, &Q, getRanQR(&P, &Q))
+}
+
+// GenerateRSAExpSetup returns an RSA key with a fresh bits-bit modulus of two safe primes
+func GenerateRSAExpSetup(ctx context.Context, random io.Reader, bits int) (*RSAExpProof, error) {
+	if bits < minSetupBitLength {
+		return nil, errors.New("GenerateRSAExpSetup requires a longer modulus")
+	}
+	P, err := GenerateSafePrime(ctx, random, bits/2)
+	if err != nil {
+		return nil, err
+	}
+	Q, err := GenerateSafePrime(ctx, random, bits-bits/2)
+	if err != nil {
+		return nil, err
+	}
+	if P.Cmp(Q) == 0 {
+		return nil, errors.New("GenerateRSAExpSetup generates equal primes")
+	}
+	base, err := randomGenerator(random, P, Q)
+	if err != nil {
+		return nil, err
+	}
+	return newRSAExpProof(P, Q, base), nil
+}
+
+func newRSAExpProof(P, Q, base *big.Int) *RSAExpProof {
 	var ret RSAExpProof
-	// ret.P = *getSafePrime()
-	// ret.Q = *getSafePrime()
-	ret.P = new(big.Int)
-	ret.Q = new(big.Int)
-	ret.P.SetString(Pstring, 10)
-	ret.Q.SetString(Qstring, 10)
+	ret.P = new(big.Int).Set(P)
+	ret.Q = new(big.Int).Set(Q)
 	ret.RSAMod = new(big.Int).Mul(ret.P, ret.Q)
 	var ptemp, qtemp big.Int
 	ptemp.Sub(ret.P, big1)
@@ -33,23 +67,46 @@ func RSAExpSetup() *RSAExpProof {
 	qtemp.Sub(ret.Q, big1)
 	qtemp.Div(&qtemp, big2)
 	ret.Order = new(big.Int).Mul(&ptemp, &qtemp)
-	ret.Base = getRanQR(ret.P, ret.Q)
+	ret.Base = base
 
-	var temp, big4, useless big.Int
-	big4.SetInt64(4)
+	// p' and q' are primes much larger than D, so D is invertible modulo phi(N) = 4*Order
 	ret.D = new(big.Int).SetInt64(publicKey)
-	ret.E = new(big.Int)
-	temp.GCD(&useless, ret.E, new(big.Int).Mul(ret.Order, &big4), ret.D)
-	if temp.Cmp(new(big.Int).SetInt64(1)) != 0 {
-		fmt.Println("Error while setting up keys!")
-		fmt.Println("e = ", ret.E.String())
-		ret.E.Mod(ret.E, new(big.Int).Mul(ret.Order, &big4))
-		fmt.Println("e = ", ret.E.String())
-		temp.Mul(ret.D, ret.E)
-		temp.Mod(&temp, new(big.Int).Mul(ret.Order, &big4))
-		fmt.Println("temp = ", temp.String())
-	}
-	temp.SetInt64(TimePara)
-	ret.Exponent = new(big.Int).Exp(big2, &temp, ret.Order)
+	ret.E = new(big.Int).ModInverse(ret.D, new(big.Int).Lsh(ret.Order, 2))
+	ret.Exponent = ret.TimeExponent(TimePara)
 	return &ret
 }
+
+// TimeExponent returns 2^T mod Order, the result is computed once per T and cached in the setup. It is safe for
+// concurrent use.
+func (setup *RSAExpProof) TimeExponent(T int64) *big.Int {
+	setup.lock.Lock()
+	defer setup.lock.Unlock()
+	if setup.exponents == nil {
+		setup.exponents = make(map[int64]*big.Int)
+	}
+	exp, ok := setup.exponents[T]
+	if !ok {
+		exp = new(big.Int).Exp(big2, big.NewInt(T), setup.Order)
+		setup.exponents[T] = exp
+	}
+	return new(big.Int).Set(exp)
+}
+
+// inverseTimeExponent returns (2^T)^{-1} mod Order, raising a quadratic residue to it undoes T squarings
+func (setup *RSAExpProof) inverseTimeExponent(T int64) *big.Int {
+	return new(big.Int).ModInverse(setup.TimeExponent(T), setup.Order)
+}
+
+// RSAExpPublic contains the public part of RSAExpProof, D is the public key
+type RSAExpPublic struct {
+	RSAMod *big.Int
+	D      *big.Int
+}
+
+// PublicPart returns the public part of the RSA key which can be handed to verifiers
+func (setup *RSAExpProof) PublicPart() *RSAExpPublic {
+	return &RSAExpPublic{
+		RSAMod: new(big.Int).Set(setup.RSAMod),
+		D:      new(big.Int).Set(setup.D),
+	}
+}
@@ -0,0 +1,204 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// batchExponentBits is the length of the random exponents combining the checks of a batch, a batch containing an
+// invalid proof passes with probability at most 2^-batchExponentBits in a group without elements of small order
+const batchExponentBits = securityPara
+
+// BatchError lists the proofs of a batch rejected by the individual verification
+type BatchError struct {
+	// Failed holds the indices of the rejected proofs in ascending order
+	Failed []int
+	// Errs holds the errors of the rejected proofs, Errs[i] belongs to Failed[i]
+	Errs []error
+}
+
+// Error returns the indices of the rejected proofs and the first error
+func (err *BatchError) Error() string {
+	indices := make([]string, len(err.Failed))
+	for i, index := range err.Failed {
+		indices[i] = strconv.Itoa(index)
+	}
+	return "batch verification rejects proofs " + strings.Join(indices, ", ") + ": " + err.Errs[0].Error()
+}
+
+// Unwrap returns the errors of the rejected proofs, errors.Is and errors.As match any of them
+func (err *BatchError) Unwrap() []error {
+	return err.Errs
+}
+
+func (err *BatchError) add(index int, e error) {
+	err.Failed = append(err.Failed, index)
+	err.Errs = append(err.Errs, e)
+}
+
+// result returns nil if no proof was rejected
+func (err *BatchError) result() error {
+	if len(err.Failed) == 0 {
+		return nil
+	}
+	return err
+}
+
+// batchEquation collects the random linear combination prod lhs[i]^lhsExp[i] = prod rhs[i]^rhsExp[i] of a batch, bases
+// which occur several times are merged into one base with the sum of their exponents
+type batchEquation struct {
+	lhs, rhs       []*big.Int
+	lhsExp, rhsExp []*big.Int
+	lhsIdx, rhsIdx map[string]int
+}
+
+func newBatchEquation() *batchEquation {
+	return &batchEquation{lhsIdx: make(map[string]int), rhsIdx: make(map[string]int)}
+}
+
+func addBase(bases, exps *[]*big.Int, index map[string]int, base, exp *big.Int) {
+	key := string(base.Bytes())
+	if i, ok := index[key]; ok {
+		(*exps)[i].Add((*exps)[i], exp)
+		return
+	}
+	index[key] = len(*bases)
+	*bases = append(*bases, base)
+	*exps = append(*exps, new(big.Int).Set(exp))
+}
+
+func (eq *batchEquation) left(base, exp *big.Int) {
+	addBase(&eq.lhs, &eq.lhsExp, eq.lhsIdx, base, exp)
+}
+
+func (eq *batchEquation) right(base, exp *big.Int) {
+	addBase(&eq.rhs, &eq.rhsExp, eq.rhsIdx, base, exp)
+}
+
+func (eq *batchEquation) holds(group Group) bool {
+	return group.Equal(group.MultiExp(eq.lhs, eq.lhsExp), group.MultiExp(eq.rhs, eq.rhsExp))
+}
+
+// batches returns true if checks in group can be combined. In Z_N^* an invalid proof which is off by -1 passes the
+// combination with probability 1/2, so proofs in an *RSAGroup are verified one by one like in the single verifiers.
+func batches(group Group) bool {
+	_, ok := group.(*RSAGroup)
+	return !ok
+}
+
+// batchExponent returns a random exponent of batchExponentBits bits
+func batchExponent() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big1, batchExponentBits))
+}
+
+// BatchPoKEStarVerify checks the proofs that g^x = C[i] with one random linear combination
+// prod Q_i^{a_i l_i} * g^{sum a_i R_i} = prod C_i^{a_i}. If the combination fails, every proof is verified on its own,
+// and the returned *BatchError identifies the invalid proofs. Proofs in Z_N^* are always verified on their own.
+func BatchPoKEStarVerify(pp *PublicParameters, C []*big.Int, proofs []*PoKEStarProof) error {
+	var ret BatchError
+	if len(C) != len(proofs) {
+		ret.add(0, malformed("PoKEStar", "batch", "has "+strconv.Itoa(len(C))+" statements for "+
+			strconv.Itoa(len(proofs))+" proofs"))
+		return &ret
+	}
+	eq := newBatchEquation()
+	var rSum big.Int
+	var batched []int
+	for i, proof := range proofs {
+		if err := proof.Validate(pp, C[i]); err != nil {
+			ret.add(i, err)
+			continue
+		}
+		l := pokeStarChallenge(nil, pp, C[i])
+		if proof.R.Cmp(l) >= 0 {
+			ret.add(i, malformed("PoKEStar", "R", "is not smaller than the challenge"))
+			continue
+		}
+		a, err := batchExponent()
+		if err != nil {
+			return err
+		}
+		eq.left(proof.Q, l.Mul(l, a))
+		rSum.Add(&rSum, new(big.Int).Mul(a, proof.R))
+		eq.right(C[i], a)
+		batched = append(batched, i)
+	}
+	if len(batched) == 0 {
+		return ret.result()
+	}
+	eq.left(pp.G, &rSum)
+	if group := pp.group(); !batches(group) || !eq.holds(group) {
+		for _, i := range batched {
+			if err := PoKEStarVerifyErr(pp, C[i], proofs[i]); err != nil {
+				ret.add(i, err)
+			}
+		}
+		sortBatchError(&ret)
+	}
+	return ret.result()
+}
+
+// PoEStatement is the statement Base^X = C of a PoE proof
+type PoEStatement struct {
+	Base *big.Int
+	C    *big.Int
+	X    *big.Int
+}
+
+// BatchPoEVerify checks the PoE proofs in group with one random linear combination
+// prod Q_i^{a_i l_i} * prod Base_i^{a_i (X_i mod l_i)} = prod C_i^{a_i}, proofs for the same base share one
+// exponentiation. If the combination fails, every proof is verified on its own, and the returned *BatchError
+// identifies the invalid proofs. Proofs in Z_N^* are always verified on their own.
+func BatchPoEVerify(group Group, statements []PoEStatement, proofs []*PoEProof) error {
+	var ret BatchError
+	if len(statements) != len(proofs) {
+		ret.add(0, malformed("PoE", "batch", "has "+strconv.Itoa(len(statements))+" statements for "+
+			strconv.Itoa(len(proofs))+" proofs"))
+		return &ret
+	}
+	eq := newBatchEquation()
+	var batched []int
+	for i, proof := range proofs {
+		st := statements[i]
+		if err := proof.Validate(group, st.Base, st.C, st.X); err != nil {
+			ret.add(i, err)
+			continue
+		}
+		var l, r big.Int
+		l.Set(poeChallenge(nil, group, st.Base, st.C, st.X))
+		r.Mod(st.X, &l)
+		a, err := batchExponent()
+		if err != nil {
+			return err
+		}
+		eq.left(proof.Q, l.Mul(&l, a))
+		eq.left(st.Base, r.Mul(&r, a))
+		eq.right(st.C, a)
+		batched = append(batched, i)
+	}
+	if len(batched) == 0 {
+		return ret.result()
+	}
+	if !batches(group) || !eq.holds(group) {
+		for _, i := range batched {
+			st := statements[i]
+			if err := PoEVerifyInGroupErr(group, st.Base, st.C, st.X, proofs[i]); err != nil {
+				ret.add(i, err)
+			}
+		}
+		sortBatchError(&ret)
+	}
+	return ret.result()
+}
+
+// sortBatchError sorts the rejected proofs by index, malformed proofs are found before the batch and the others after
+func sortBatchError(err *BatchError) {
+	for i := 1; i < len(err.Failed); i++ {
+		for j := i; j > 0 && err.Failed[j-1] > err.Failed[j]; j-- {
+			err.Failed[j-1], err.Failed[j] = err.Failed[j], err.Failed[j-1]
+			err.Errs[j-1], err.Errs[j] = err.Errs[j], err.Errs[j-1]
+		}
+	}
+}
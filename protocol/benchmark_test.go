@@ -1,15 +1,22 @@
 package protocol
 
 import (
+	"context"
+	crand "crypto/rand"
 	"math/big"
+	"strconv"
 	"testing"
+	"time"
 )
 
 const expBenchmark int64 = 8
 
+// vdfBenchmarkT is the hardness of the puzzles in the VDF benchmarks
+const vdfBenchmarkT int64 = 1 << 16
+
 func BenchmarkZKPoMoDEProve(b *testing.B) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, x2e, n, xmod big.Int //x2e = x^e
 	x.Set(setup.G)
 	e.SetInt64(expBenchmark)
@@ -26,7 +33,7 @@ func BenchmarkZKPoMoDEProve(b *testing.B) {
 
 func BenchmarkZKPoMoDEVerify(b *testing.B) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, x2e, n, xmod big.Int //x2e = x^e
 	x.Set(setup.G)
 	e.SetInt64(expBenchmark)
@@ -44,7 +51,7 @@ func BenchmarkZKPoMoDEVerify(b *testing.B) {
 
 func BenchmarkZKPoMoDEFastProve(b *testing.B) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, x2e, n, xmod big.Int //x2e = x^e
 	x.Set(setup.G)
 	e.SetInt64(expBenchmark)
@@ -61,7 +68,7 @@ func BenchmarkZKPoMoDEFastProve(b *testing.B) {
 
 func BenchmarkZKPoMoDEFastVerify(b *testing.B) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, x2e, n, xmod big.Int //x2e = x^e
 	x.Set(setup.G)
 	e.SetInt64(expBenchmark)
@@ -76,3 +83,258 @@ func BenchmarkZKPoMoDEFastVerify(b *testing.B) {
 		_ = ZKPoMoDEFastVerify(&pp, &C1, &C2, &n, &e, &xmod, proof)
 	}
 }
+
+func benchmarkVDFSolve(b *testing.B) (*Puzzle, *solverState) {
+	rsasetup := RSAExpSetup()
+	puzzle, err := rsasetup.NewPuzzle(GenVRFSolution([]byte("VTLP benchmark"), rsasetup), vdfBenchmarkT)
+	if err != nil {
+		b.Fatal(err)
+	}
+	state, err := solve(context.Background(), puzzle, nil, proofInterval(vdfBenchmarkT))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return puzzle, state
+}
+
+func benchmarkVDFProve(b *testing.B, vdf VDF) {
+	puzzle, state := benchmarkVDFSolve(b)
+	var size int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		proof, _ := vdf.Prove(puzzle, state.y, state.points, state.interval)
+		data, _ := proof.MarshalBinary()
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "proof-bytes")
+}
+
+func benchmarkVDFVerify(b *testing.B, vdf VDF) {
+	puzzle, state := benchmarkVDFSolve(b)
+	proof, _ := vdf.Prove(puzzle, state.y, state.points, state.interval)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = vdf.Verify(puzzle, state.y, proof)
+	}
+}
+
+func BenchmarkWesolowskiProve(b *testing.B) {
+	benchmarkVDFProve(b, WesolowskiVDF{})
+}
+
+func BenchmarkWesolowskiVerify(b *testing.B) {
+	benchmarkVDFVerify(b, WesolowskiVDF{})
+}
+
+func BenchmarkPietrzakProve(b *testing.B) {
+	benchmarkVDFProve(b, PietrzakVDF{})
+}
+
+func BenchmarkPietrzakVerify(b *testing.B) {
+	benchmarkVDFVerify(b, PietrzakVDF{})
+}
+
+func BenchmarkGenerateSafePrime512Sequential(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = generateSafePrime(context.Background(), crand.Reader, 512, 1)
+	}
+}
+
+func BenchmarkGenerateSafePrime512(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = GenerateSafePrime(context.Background(), crand.Reader, 512)
+	}
+}
+
+func BenchmarkGenerateSafePrime1024(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = GenerateSafePrime(context.Background(), crand.Reader, 1024)
+	}
+}
+
+func benchmarkClassGroupForm(b *testing.B) (*ClassGroup, *Form) {
+	group, err := ClassGroupFromSeed([]byte("VTLP benchmark"), 1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return group, group.exp(group.Generator(), Min1024)
+}
+
+func BenchmarkClassGroupSquare(b *testing.B) {
+	group, f := benchmarkClassGroupForm(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f = group.square(f)
+	}
+}
+
+func BenchmarkClassGroupCompose(b *testing.B) {
+	group, f := benchmarkClassGroupForm(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f = group.compose(f, f)
+	}
+}
+
+const batchBenchmarkSize = 64
+
+func benchmarkBatchParameters(b *testing.B) (*PublicParameters, []*big.Int, []*PoKEStarProof, []PoEStatement, []*PoEProof) {
+	setup := TrustedSetup()
+	group := NewSignedQRGroup(setup.N)
+	pp := NewGroupParameters(group, group.Element(setup.G), group.Element(setup.H))
+	C, pokeStar, statements, poe := genBatch(b, pp, batchBenchmarkSize)
+	return pp, C, pokeStar, statements, poe
+}
+
+func BenchmarkPoKEStarVerifyLoop(b *testing.B) {
+	pp, C, pokeStar, _, _ := benchmarkBatchParameters(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range pokeStar {
+			_ = PoKEStarVerify(pp, C[j], pokeStar[j])
+		}
+	}
+}
+
+func BenchmarkPoKEStarVerifyBatch(b *testing.B) {
+	pp, C, pokeStar, _, _ := benchmarkBatchParameters(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = BatchPoKEStarVerify(pp, C, pokeStar)
+	}
+}
+
+func BenchmarkPoEVerifyLoop(b *testing.B) {
+	pp, _, _, statements, poe := benchmarkBatchParameters(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, st := range statements {
+			_ = PoEVerifyInGroup(pp.Group, st.Base, st.C, st.X, poe[j])
+		}
+	}
+}
+
+func BenchmarkPoEVerifyBatch(b *testing.B) {
+	pp, _, _, statements, poe := benchmarkBatchParameters(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = BatchPoEVerify(pp.Group, statements, poe)
+	}
+}
+
+// BenchmarkSquarer compares the squarings per second of a Squarer with Mul and Mod of math/big
+func BenchmarkSquarer(b *testing.B) {
+	for _, bits := range []int{1024, 2048, 3072, 4096} {
+		N, _ := crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(bits)))
+		N.SetBit(N, bits-1, 1)
+		N.SetBit(N, 0, 1)
+		x, _ := crand.Int(crand.Reader, N)
+		b.Run("RSA"+strconv.Itoa(bits)+"/big", func(b *testing.B) {
+			var y, temp big.Int
+			y.Set(x)
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				temp.Mul(&y, &y)
+				y.Mod(&temp, N)
+			}
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "squarings/s")
+		})
+		b.Run("RSA"+strconv.Itoa(bits)+"/squarer", func(b *testing.B) {
+			squarer := NewSquarer(N, x)
+			start := time.Now()
+			squarer.SquareN(int64(b.N))
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "squarings/s")
+		})
+	}
+}
+
+// BenchmarkMultiExp compares one exponentiation per base with the multi-exponentiation for the shapes of the
+// verifier equations: Q^l * g^r, the three bases of ZKPoKE, two blinding exponents of a prover and a batch
+func BenchmarkMultiExp(b *testing.B) {
+	type benchmarkGroup struct {
+		name  string
+		group Group
+		g     *big.Int
+	}
+	var groups []benchmarkGroup
+	for _, bits := range []int{2048, 3072} {
+		N, _ := crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(bits)))
+		N.SetBit(N, bits-1, 1)
+		N.SetBit(N, 0, 1)
+		g, _ := crand.Int(crand.Reader, N)
+		groups = append(groups, benchmarkGroup{"RSA" + strconv.Itoa(bits), NewRSAGroup(N), g})
+	}
+	classGroup, f := benchmarkClassGroupForm(b)
+	formGroup := classGroup.AsGroup()
+	groups = append(groups, benchmarkGroup{"ClassGroup1024", formGroup, formGroup.Element(f)})
+
+	for _, bg := range groups {
+		shapes := []struct {
+			name  string
+			count int
+			bits  int
+		}{
+			{"verifier-2", 2, 240},
+			{"verifier-3", 3, 240},
+			{"prover-2", 2, blindingLength(bg.group)},
+			{"batch-64", 64, 240 + batchExponentBits},
+		}
+		for _, shape := range shapes {
+			bases := make([]*big.Int, shape.count)
+			exps := make([]*big.Int, shape.count)
+			for i := range bases {
+				r, _ := crand.Int(crand.Reader, big.NewInt(1<<20))
+				bases[i] = bg.group.Exp(bg.g, r)
+				exps[i], _ = crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(shape.bits)))
+			}
+			b.Run(bg.name+"/"+shape.name+"/separate", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					_ = multiExpSeparately(bg.group, bases, exps)
+				}
+			})
+			b.Run(bg.name+"/"+shape.name+"/simultaneous", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					_ = bg.group.MultiExp(bases, exps)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkFixedBaseExp compares big.Int.Exp with the fixed-base table for an exponent of the blinding length
+func BenchmarkFixedBaseExp(b *testing.B) {
+	setup := TrustedSetup()
+	group := NewRSAGroup(setup.N)
+	table := NewFixedBase(group, setup.G, 2*blindingLength(group))
+	e, _ := crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(blindingLength(group))))
+	b.Run("Exp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = group.Exp(setup.G, e)
+		}
+	})
+	b.Run("FixedBase", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = table.Exp(group, e)
+		}
+	})
+}
+
+func BenchmarkZKPoMoDEFastProvePrecomputed(b *testing.B) {
+	setup := TrustedSetup()
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	pp.Precompute(0)
+	var x, C1, C2, e, x2e, n, xmod big.Int //x2e = x^e
+	x.Set(setup.G)
+	e.SetInt64(expBenchmark)
+	n.Set(setup.N)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Exp(setup.G, &x, setup.N)
+	C2.Exp(setup.G, &x2e, setup.N)
+	// build the tables outside of the timed loop
+	_ = pp.expG(big1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ZKPoMoDEFastProve(&pp, &C1, &C2, &n, &e, &xmod, &x)
+	}
+}
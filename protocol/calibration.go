@@ -0,0 +1,149 @@
+package protocol
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math"
+	"math/big"
+	"time"
+)
+
+const (
+	// calibrationBatch is the number of squarings between two reads of the clock
+	calibrationBatch = 256
+	// calibrationZ is the z-score of the two-sided 99% confidence interval of the measured rate
+	calibrationZ = 2.576
+	// hoursPerYear is used to compound SafetyModel.YearlySpeedup
+	hoursPerYear = 365.25 * 24
+)
+
+// CalibrationOptions configures Calibrate, zero fields take the defaults below
+type CalibrationOptions struct {
+	// Warmup is spent squaring before measuring, so that caches and CPU frequency settle, 200ms by default
+	Warmup time.Duration
+	// Samples is the number of measurements the confidence interval is computed from, 10 by default
+	Samples int
+	// SampleDuration is the length of one measurement, 100ms by default
+	SampleDuration time.Duration
+}
+
+// Calibration contains the measured speed of sequential squaring mod a Bits-bit modulus on this machine
+type Calibration struct {
+	Bits int
+	// Rate is the mean number of squarings per second, StdDev its standard deviation over the samples
+	Rate    float64
+	StdDev  float64
+	Samples int
+}
+
+// SafetyModel describes how much faster than the calibrated machine an adversary can square
+type SafetyModel struct {
+	// HardwareSpeedup is the speedup of the adversary today, e.g. with an ASIC, values below 1 count as 1
+	HardwareSpeedup float64
+	// YearlySpeedup is the yearly improvement of the adversary compounded over the delay, 0.1 means 10% per year
+	YearlySpeedup float64
+}
+
+// Factor returns the speedup of the adversary for a puzzle locked for delay
+func (model SafetyModel) Factor(delay time.Duration) float64 {
+	factor := math.Max(model.HardwareSpeedup, 1)
+	if model.YearlySpeedup > 0 {
+		factor *= math.Pow(1+model.YearlySpeedup, delay.Hours()/hoursPerYear)
+	}
+	return factor
+}
+
+// Calibrate measures the squarings per second mod a random bits-bit modulus on this machine
+func Calibrate(ctx context.Context, bits int, opts *CalibrationOptions) (*Calibration, error) {
+	if bits < 2 {
+		return nil, errors.New("Calibrate requires a modulus of at least 2 bits")
+	}
+	var options CalibrationOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Warmup <= 0 {
+		options.Warmup = 200 * time.Millisecond
+	}
+	if options.Samples <= 0 {
+		options.Samples = 10
+	}
+	if options.SampleDuration <= 0 {
+		options.SampleDuration = 100 * time.Millisecond
+	}
+
+	// the running time of a squaring only depends on the size of the modulus
+	mod, err := rand.Int(rand.Reader, new(big.Int).Lsh(big1, uint(bits-1)))
+	if err != nil {
+		return nil, err
+	}
+	mod.SetBit(mod, bits-1, 1)
+	mod.SetBit(mod, 0, 1)
+	y, err := rand.Int(rand.Reader, mod)
+	if err != nil {
+		return nil, err
+	}
+
+	measure := func(duration time.Duration) (float64, error) {
+		var count int64
+		squarer := NewSquarer(mod, y)
+		start := time.Now()
+		for {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			squarer.SquareN(calibrationBatch)
+			count += calibrationBatch
+			if elapsed := time.Since(start); elapsed >= duration {
+				return float64(count) / elapsed.Seconds(), nil
+			}
+		}
+	}
+	if _, err = measure(options.Warmup); err != nil {
+		return nil, err
+	}
+	rates := make([]float64, options.Samples)
+	var sum float64
+	for i := range rates {
+		if rates[i], err = measure(options.SampleDuration); err != nil {
+			return nil, err
+		}
+		sum += rates[i]
+	}
+	ret := Calibration{Bits: bits, Rate: sum / float64(len(rates)), Samples: len(rates)}
+	if len(rates) > 1 {
+		var squares float64
+		for _, rate := range rates {
+			squares += (rate - ret.Rate) * (rate - ret.Rate)
+		}
+		ret.StdDev = math.Sqrt(squares / float64(len(rates)-1))
+	}
+	return &ret, nil
+}
+
+// UpperRate returns the upper end of the 99% confidence interval of Rate
+func (calibration *Calibration) UpperRate() float64 {
+	return calibration.Rate + calibrationZ*calibration.StdDev/math.Sqrt(float64(calibration.Samples))
+}
+
+// SquaringsFor returns the time parameter T of a puzzle that an adversary following model can not solve faster than delay.
+// It is based on UpperRate, so that the puzzle is not too easy because of measurement noise.
+func (calibration *Calibration) SquaringsFor(delay time.Duration, model SafetyModel) (int64, error) {
+	if delay <= 0 {
+		return 0, errors.New("SquaringsFor requires a positive delay")
+	}
+	if calibration.Samples <= 0 || calibration.Rate <= 0 {
+		return 0, errors.New("SquaringsFor requires a calibrated rate")
+	}
+	T := math.Ceil(delay.Seconds() * calibration.UpperRate() * model.Factor(delay))
+	if T >= math.MaxInt64 {
+		return 0, errors.New("SquaringsFor overflows the time parameter")
+	}
+	return int64(math.Max(T, 1)), nil
+}
+
+// Duration returns how long solving a puzzle with time parameter T takes on the calibrated machine
+func (calibration *Calibration) Duration(T int64) time.Duration {
+	return time.Duration(float64(T) / calibration.Rate * float64(time.Second))
+}
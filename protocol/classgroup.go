@@ -0,0 +1,368 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+var (
+	big4 = big.NewInt(4)
+
+	errInvalidForm = errors.New("form is not a primitive positive definite form of the discriminant")
+)
+
+// ClassGroup is the class group of the imaginary quadratic order of discriminant D, a negative D = 1 mod 8. Its order is
+// unknown and can not be computed from D without a class number computation, so no trusted setup is needed.
+type ClassGroup struct {
+	D *big.Int
+	// L = floor(|D/4|^{1/4}) bounds the partial Euclid in NUDUPL
+	L *big.Int
+}
+
+// Form is the binary quadratic form A*x^2 + B*x*y + C*y^2 of discriminant B^2 - 4AC = D, the group operations return
+// reduced forms, which are the unique representatives of the classes
+type Form struct {
+	A *big.Int
+	B *big.Int
+	C *big.Int
+}
+
+// NewClassGroup returns the class group of discriminant D
+func NewClassGroup(D *big.Int) (*ClassGroup, error) {
+	if D.Sign() >= 0 || new(big.Int).Mod(D, big.NewInt(8)).Int64() != 1 {
+		return nil, errors.New("NewClassGroup requires a negative discriminant which is 1 mod 8")
+	}
+	var L big.Int
+	L.Abs(D)
+	L.Rsh(&L, 2)
+	L.Sqrt(&L)
+	L.Sqrt(&L)
+	return &ClassGroup{D: new(big.Int).Set(D), L: &L}, nil
+}
+
+// ClassGroupFromSeed derives the discriminant D = -p from a public seed, where p = 7 mod 8 is a bits-bit prime.
+// Anyone can recompute D from the seed, so nobody can have chosen it with a known class number.
+func ClassGroupFromSeed(seed []byte, bits int) (*ClassGroup, error) {
+	if bits < 16 {
+		return nil, errors.New("ClassGroupFromSeed requires a longer discriminant")
+	}
+	var buf []byte
+	for block := 0; len(buf)*8 < bits; block++ {
+		digest := sha256.Sum256(append([]byte("VTLP class group "+strconv.Itoa(block)+" "), seed...))
+		buf = append(buf, digest[:]...)
+	}
+	p := new(big.Int).SetBytes(buf)
+	p.Rsh(p, uint(len(buf)*8-bits))
+	p.SetBit(p, bits-1, 1)
+	// p = 7 mod 8, so that D = -p = 1 mod 8
+	p.SetBit(p, 0, 1)
+	p.SetBit(p, 1, 1)
+	p.SetBit(p, 2, 1)
+	eight := big.NewInt(8)
+	for !p.ProbablyPrime(securityParaHashToPrime) {
+		p.Add(p, eight)
+	}
+	return NewClassGroup(p.Neg(p))
+}
+
+// Identity returns the principal form (1, 1, (1-D)/4)
+func (group *ClassGroup) Identity() *Form {
+	c := new(big.Int).Sub(big1, group.D)
+	return &Form{A: big.NewInt(1), B: big.NewInt(1), C: c.Rsh(c, 2)}
+}
+
+// Generator returns the class of the form (2, 1, (1-D)/8), which exists because D = 1 mod 8
+func (group *ClassGroup) Generator() *Form {
+	c := new(big.Int).Sub(big1, group.D)
+	return group.Reduce(&Form{A: big.NewInt(2), B: big.NewInt(1), C: c.Rsh(c, 3)})
+}
+
+// NewForm returns the reduced form of the class of (a, b, c) where c is computed from the discriminant
+func (group *ClassGroup) NewForm(a, b *big.Int) (*Form, error) {
+	if a.Sign() <= 0 {
+		return nil, errors.New("NewForm requires a positive a")
+	}
+	var c, r big.Int
+	c.Mul(b, b)
+	c.Sub(&c, group.D)
+	c.QuoRem(&c, new(big.Int).Mul(big4, a), &r)
+	if r.Sign() != 0 {
+		return nil, errors.New("NewForm inputs a and b without a form of this discriminant")
+	}
+	return group.Reduce(&Form{A: new(big.Int).Set(a), B: new(big.Int).Set(b), C: &c}), nil
+}
+
+// IsValid returns true if f is a reduced form of discriminant D
+func (group *ClassGroup) IsValid(f *Form) bool {
+	return group.isForm(f) && isReduced(f)
+}
+
+// isForm returns true if f is a primitive positive definite form of discriminant D, the forms whose classes make up the
+// group
+func (group *ClassGroup) isForm(f *Form) bool {
+	if f == nil || f.A == nil || f.B == nil || f.C == nil || f.A.Sign() <= 0 {
+		return false
+	}
+	var d, temp big.Int
+	d.Mul(f.B, f.B)
+	temp.Mul(f.A, f.C)
+	temp.Lsh(&temp, 2)
+	d.Sub(&d, &temp)
+	if d.Cmp(group.D) != 0 {
+		return false
+	}
+	temp.GCD(nil, nil, f.A, d.Abs(f.B))
+	return temp.GCD(nil, nil, &temp, f.C).Cmp(big1) == 0
+}
+
+// isReduced returns true if |B| <= A <= C, with B >= 0 if |B| = A or A = C
+func isReduced(f *Form) bool {
+	if f.B.CmpAbs(f.A) > 0 || f.A.Cmp(f.C) > 0 {
+		return false
+	}
+	if (f.B.CmpAbs(f.A) == 0 || f.A.Cmp(f.C) == 0) && f.B.Sign() < 0 {
+		return false
+	}
+	return true
+}
+
+// normalize makes -A < B <= A without changing the class
+func normalize(f *Form) {
+	var r, temp, twoA big.Int
+	twoA.Lsh(f.A, 1)
+	if f.B.Cmp(new(big.Int).Neg(f.A)) > 0 && f.B.Cmp(f.A) <= 0 {
+		return
+	}
+	// r = floor((A - B) / 2A), B += 2rA, C += r(rA + B)
+	r.Sub(f.A, f.B)
+	r.Div(&r, &twoA)
+	temp.Mul(&r, f.A)
+	temp.Add(&temp, f.B)
+	temp.Mul(&temp, &r)
+	f.C.Add(f.C, &temp)
+	temp.Mul(&r, &twoA)
+	f.B.Add(f.B, &temp)
+}
+
+// Reduce returns the reduced form in the class of f
+func (group *ClassGroup) Reduce(f *Form) *Form {
+	ret := &Form{A: new(big.Int).Set(f.A), B: new(big.Int).Set(f.B), C: new(big.Int).Set(f.C)}
+	normalize(ret)
+	for ret.A.Cmp(ret.C) > 0 || (ret.A.Cmp(ret.C) == 0 && ret.B.Sign() < 0) {
+		// (A, B, C) -> (C, -B, A)
+		ret.A, ret.C = ret.C, ret.A
+		ret.B.Neg(ret.B)
+		normalize(ret)
+	}
+	return ret
+}
+
+// Inverse returns the inverse class (A, -B, C)
+func (group *ClassGroup) Inverse(f *Form) *Form {
+	return group.Reduce(&Form{A: f.A, B: new(big.Int).Neg(f.B), C: f.C})
+}
+
+// solveMod solves a*x = b mod m, all solutions are x = s + t*k
+func solveMod(a, b, m *big.Int) (*big.Int, *big.Int, error) {
+	var g, d, q, r big.Int
+	// GCD requires positive inputs, the sign of a is moved to d
+	g.GCD(&d, nil, new(big.Int).Abs(a), m)
+	if a.Sign() < 0 {
+		d.Neg(&d)
+	}
+	q.DivMod(b, &g, &r)
+	if r.Sign() != 0 {
+		return nil, nil, errors.New("solveMod inputs an equation without solution")
+	}
+	s := new(big.Int).Mul(&q, &d)
+	s.Mod(s, m)
+	return s, new(big.Int).Div(m, &g), nil
+}
+
+// Compose returns the reduced product of the classes of f1 and f2, or an error if one of them is not a primitive
+// positive definite form of discriminant D
+func (group *ClassGroup) Compose(f1, f2 *Form) (*Form, error) {
+	if !group.isForm(f1) || !group.isForm(f2) {
+		return nil, errInvalidForm
+	}
+	return group.compose(f1, f2), nil
+}
+
+// compose returns the reduced product of the classes of the valid forms f1 and f2, following the composition algorithm
+// in Long, "Binary Quadratic Forms"
+func (group *ClassGroup) compose(f1, f2 *Form) *Form {
+	var g, h, w, s, t, u, temp big.Int
+	g.Add(f1.B, f2.B)
+	g.Rsh(&g, 1)
+	h.Sub(f2.B, f1.B)
+	h.Rsh(&h, 1)
+	w.GCD(nil, nil, f1.A, f2.A)
+	w.GCD(nil, nil, &w, new(big.Int).Abs(&g))
+	s.Div(f1.A, &w)
+	t.Div(f2.A, &w)
+	u.Div(&g, &w)
+
+	// solve k*t - l*s = h, k*u - m*s = C2, l*u - m*t = C1 for k, l, m
+	var tu, st, rhs big.Int
+	tu.Mul(&t, &u)
+	st.Mul(&s, &t)
+	rhs.Mul(&h, &u)
+	rhs.Add(&rhs, temp.Mul(&s, f1.C))
+	kTemp, factor, err := solveMod(&tu, &rhs, &st)
+	if err != nil {
+		// the equations always have a solution for primitive forms of the same discriminant
+		panic(err)
+	}
+	rhs.Mul(&t, kTemp)
+	rhs.Sub(&h, &rhs)
+	n, _, err := solveMod(temp.Mul(&t, factor), &rhs, &s)
+	if err != nil {
+		panic(err)
+	}
+	k := new(big.Int).Mul(factor, n)
+	k.Add(k, kTemp)
+	var l, m big.Int
+	l.Mul(&t, k)
+	l.Sub(&l, &h)
+	l.Div(&l, &s)
+	m.Mul(&tu, k)
+	m.Sub(&m, temp.Mul(&h, &u))
+	m.Sub(&m, temp.Mul(&s, f1.C))
+	m.Div(&m, &st)
+
+	ret := &Form{A: new(big.Int).Set(&st), B: new(big.Int), C: new(big.Int)}
+	// B = w*u - (k*t + l*s), C = k*l - w*m
+	ret.B.Mul(&w, &u)
+	ret.B.Sub(ret.B, temp.Mul(k, &t))
+	ret.B.Sub(ret.B, temp.Mul(&l, &s))
+	ret.C.Mul(k, &l)
+	ret.C.Sub(ret.C, temp.Mul(&w, &m))
+	return group.Reduce(ret)
+}
+
+// partialEuclid runs the extended Euclid on (r2, r1) until r1 <= L, keeping the cofactors co2 and co1
+func partialEuclid(co2, co1, r2, r1, L *big.Int) {
+	var q, r, temp big.Int
+	co2.SetInt64(0)
+	co1.SetInt64(-1)
+	for r1.Sign() != 0 && r1.Cmp(L) > 0 {
+		q.DivMod(r2, r1, &r)
+		temp.Mul(&q, co1)
+		temp.Sub(co2, &temp)
+		co2.Set(co1)
+		co1.Set(&temp)
+		r2.Set(r1)
+		r1.Set(&r)
+	}
+}
+
+// Square returns the reduced square of the class of f, or an error if f is not a primitive positive definite form of
+// discriminant D
+func (group *ClassGroup) Square(f *Form) (*Form, error) {
+	if !group.isForm(f) {
+		return nil, errInvalidForm
+	}
+	return group.square(f), nil
+}
+
+// square returns the reduced square of the class of the valid form f with NUDUPL, see Jacobson and van der Poorten,
+// "Computational aspects of NUCOMP". The partial Euclid keeps the intermediate form small, so squaring is cheaper than
+// compose(f, f).
+func (group *ClassGroup) square(f *Form) *Form {
+	var G, y, By, Dy, bx, by, dx, temp big.Int
+	// G = gcd(B, A) = y*B + _*A
+	G.GCD(&y, nil, new(big.Int).Abs(f.B), f.A)
+	if f.B.Sign() < 0 {
+		y.Neg(&y)
+	}
+	By.Div(f.A, &G)
+	Dy.Div(f.B, &G)
+	bx.Mul(&y, f.C)
+	bx.Mod(&bx, &By)
+	by.Set(&By)
+
+	ret := &Form{A: new(big.Int), B: new(big.Int), C: new(big.Int)}
+	if by.CmpAbs(group.L) <= 0 {
+		// dx = (bx*Dy - C) / By, A' = by^2, B' = B - 2*bx*by, C' = bx^2 - G*dx
+		dx.Mul(&bx, &Dy)
+		dx.Sub(&dx, f.C)
+		dx.Div(&dx, &By)
+		ret.A.Mul(&by, &by)
+		ret.C.Mul(&bx, &bx)
+		temp.Add(&bx, &by)
+		temp.Mul(&temp, &temp)
+		ret.B.Sub(f.B, &temp)
+		ret.B.Add(ret.B, ret.A)
+		ret.B.Add(ret.B, ret.C)
+		ret.C.Sub(ret.C, temp.Mul(&G, &dx))
+		return group.Reduce(ret)
+	}
+
+	var x, ax, ay, dy, q1 big.Int
+	partialEuclid(&y, &x, &by, &bx, group.L)
+	x.Neg(&x)
+	if x.Sign() > 0 {
+		y.Neg(&y)
+	} else {
+		by.Neg(&by)
+	}
+	ax.Mul(&G, &x)
+	ay.Mul(&G, &y)
+
+	// dx = (Dy*bx - C*x) / By, dy = (y*dx + Dy) / x
+	dx.Mul(&Dy, &bx)
+	dx.Sub(&dx, temp.Mul(f.C, &x))
+	dx.Quo(&dx, &By)
+	q1.Mul(&y, &dx)
+	dy.Add(&q1, &Dy)
+	ret.B.Add(&dy, &q1)
+	ret.B.Mul(ret.B, &G)
+	dy.Quo(&dy, &x)
+
+	ret.A.Mul(&by, &by)
+	ret.C.Mul(&bx, &bx)
+	temp.Add(&bx, &by)
+	temp.Mul(&temp, &temp)
+	ret.B.Sub(ret.B, &temp)
+	ret.B.Add(ret.B, ret.A)
+	ret.B.Add(ret.B, ret.C)
+	ret.A.Sub(ret.A, temp.Mul(&ay, &dy))
+	ret.C.Sub(ret.C, temp.Mul(&ax, &dx))
+	return group.Reduce(ret)
+}
+
+// Exp returns f^x, or an error if f is not a primitive positive definite form of discriminant D
+func (group *ClassGroup) Exp(f *Form, x *big.Int) (*Form, error) {
+	if !group.isForm(f) {
+		return nil, errInvalidForm
+	}
+	return group.exp(f, x), nil
+}
+
+// exp returns f^x for the valid form f with left-to-right square and multiply, a negative x raises the inverse class
+// (A, -B, C) to -x
+func (group *ClassGroup) exp(f *Form, x *big.Int) *Form {
+	if x.Sign() < 0 {
+		f, x = group.Inverse(f), new(big.Int).Neg(x)
+	}
+	ret := group.Identity()
+	for i := x.BitLen() - 1; i >= 0; i-- {
+		ret = group.square(ret)
+		if x.Bit(i) == 1 {
+			ret = group.compose(ret, f)
+		}
+	}
+	return ret
+}
+
+// Equal returns true if f and g are the same form, for reduced forms this means the same class
+func (f *Form) Equal(g *Form) bool {
+	return f.A.Cmp(g.A) == 0 && f.B.Cmp(g.B) == 0 && f.C.Cmp(g.C) == 0
+}
+
+// String returns the form as (A, B, C)
+func (f *Form) String() string {
+	return "(" + f.A.String() + ", " + f.B.String() + ", " + f.C.String() + ")"
+}
@@ -1,8 +1,11 @@
 package protocol
 
 import (
+	"context"
 	crand "crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"strconv"
 
@@ -51,19 +54,8 @@ func TrustedSetup() *Setup {
 }
 
 var (
-	big1  = big.NewInt(1)
-	big2  = big.NewInt(2)
-	big3  = big.NewInt(3)
-	big5  = big.NewInt(5)
-	big7  = big.NewInt(7)
-	big11 = big.NewInt(11)
-	big13 = big.NewInt(13)
-	big17 = big.NewInt(17)
-	big19 = big.NewInt(19)
-	big23 = big.NewInt(23)
-	big29 = big.NewInt(29)
-	big31 = big.NewInt(31)
-	big37 = big.NewInt(37)
+	big1 = big.NewInt(1)
+	big2 = big.NewInt(2)
 	// Min1024 is set to a 1024 bits number with most significant bit 1 and other bits 0
 	// This can speed up the calculation
 	// Min1024 is set to 2^1023
@@ -85,11 +77,16 @@ type Setup struct {
 	H *big.Int //another generator in Z*_N
 }
 
-// PublicParameters holds public parameters initialized during the setup procedure
+// PublicParameters holds public parameters initialized during the setup procedure, the proofs run in Group, or in
+// QR_N^+ if Group is nil. Unlike Z_N^* it contains neither -1 nor other known elements of small order, so G, H and every
+// element of a statement or proof must be a canonical element |x| <= (N-1)/2 with Jacobi symbol 1.
 type PublicParameters struct {
-	N *big.Int
-	G *big.Int
-	H *big.Int
+	N     *big.Int
+	G     *big.Int
+	H     *big.Int
+	Group Group
+	// fixed holds the fixed-base tables of G and H attached by Precompute or LoadPrecomputed
+	fixed *fixedBases
 }
 
 // NewPublicParameters generates a new public parameter configuration
@@ -101,6 +98,23 @@ func NewPublicParameters(n, g, h *big.Int) *PublicParameters {
 	}
 }
 
+// NewGroupParameters returns public parameters with generators g and h of group
+func NewGroupParameters(group Group, g, h *big.Int) *PublicParameters {
+	return &PublicParameters{
+		G:     g,
+		H:     h,
+		Group: group,
+	}
+}
+
+// group returns the group of the public parameters
+func (pp *PublicParameters) group() Group {
+	if pp.Group != nil {
+		return pp.Group
+	}
+	return NewSignedQRGroup(pp.N)
+}
+
 // Element should be able to be accumulated into RSA accumulator
 type Element []byte
 
@@ -256,59 +270,69 @@ func GetPseudoRandomElement(input int) *Element {
 	return &ret
 }
 
-// TrustedSetupForQRN outputs a hidden order group
-func TrustedSetupForQRN() {
-	var p, q, N, g, h big.Int
-	p = *getSafePrime()
-	q = *getSafePrime()
-	fmt.Println("Bit length of p = ", p.BitLen())
-	fmt.Println("Bit length of q = ", q.BitLen())
-	N.Mul(&p, &q)
-
-	g = *getRanQR(&p, &q)
-	// get a uniform random value randomNum in the QR_N, where the order of the group is p'q'
-	randomNum, err := crand.Prime(crand.Reader, RSABitLength)
+// minSetupBitLength is the smallest modulus accepted by TrustedSetupForQRN, anything this small is for tests only
+const minSetupBitLength = 64
+
+// TrustedSetupForQRN outputs a hidden order group QR_N with a fresh bits-bit modulus N = p*q for safe primes p and q,
+// G and H are returned as elements of QR_N^+.
+// The trapdoor p, q is returned separately and must be deleted after the setup, whoever knows it can break the proofs.
+func TrustedSetupForQRN(ctx context.Context, random io.Reader, bits int) (*Setup, *big.Int, *big.Int, error) {
+	if bits < minSetupBitLength {
+		return nil, nil, nil, errors.New("TrustedSetupForQRN requires a longer modulus")
+	}
+	p, err := GenerateSafePrime(ctx, random, bits/2)
 	if err != nil {
-		panic(err)
+		return nil, nil, nil, err
 	}
-	order := getOrder(&p, &q)
-	randomNum.Mod(randomNum, order)
-	h.Exp(&g, randomNum, &N)
-	fmt.Println("N = ", N.String())
-	fmt.Println("g = ", g.String())
-	fmt.Println("h = ", h.String())
-}
-
-// RandomSetupForUniversalHash generates parameters for a universal hash.
-func RandomSetupForUniversalHash() {
-	p := getPrime256()
-	a, err := crand.Int(crand.Reader, p)
+	q, err := GenerateSafePrime(ctx, random, bits-bits/2)
 	if err != nil {
-		panic(err)
+		return nil, nil, nil, err
+	}
+	if p.Cmp(q) == 0 {
+		return nil, nil, nil, errors.New("TrustedSetupForQRN generates equal primes")
 	}
-	b, err := crand.Int(crand.Reader, p)
+	ret := &Setup{N: new(big.Int).Mul(p, q)}
+	if ret.G, err = randomGenerator(random, p, q); err != nil {
+		return nil, nil, nil, err
+	}
+	// h = g^r for a uniform random r in [0, p'q'), the order of QR_N
+	r, err := crand.Int(random, getOrder(p, q))
 	if err != nil {
-		panic(err)
+		return nil, nil, nil, err
 	}
-	fmt.Println("P = ", p.String())
-	fmt.Println("A = ", a.String())
-	fmt.Println("B = ", b.String())
+	group := NewSignedQRGroup(ret.N)
+	ret.G = group.Element(ret.G)
+	ret.H = group.Exp(ret.G, r)
+	return ret, p, q, nil
 }
 
-func getPrime256() *big.Int {
-	flag := false
-	for !flag {
-		ranNum, err := crand.Prime(crand.Reader, securityPara*2)
-		if err != nil {
-			panic(err)
-		}
-		flag = ranNum.ProbablyPrime(securityPara / 2)
-		if !flag {
-			continue
-		}
-		return ranNum
+// UniversalHashParameters contains the prime P and the coefficients A, B of the universal hash
+type UniversalHashParameters struct {
+	P *big.Int
+	A *big.Int
+	B *big.Int
+}
+
+// RandomSetupForUniversalHash generates parameters for a universal hash with a bits-bit prime P.
+func RandomSetupForUniversalHash(ctx context.Context, random io.Reader, bits int) (*UniversalHashParameters, error) {
+	if bits < 2 {
+		return nil, errors.New("RandomSetupForUniversalHash requires a longer prime")
 	}
-	return nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var ret UniversalHashParameters
+	var err error
+	if ret.P, err = crand.Prime(random, bits); err != nil {
+		return nil, err
+	}
+	if ret.A, err = crand.Int(random, ret.P); err != nil {
+		return nil, err
+	}
+	if ret.B, err = crand.Int(random, ret.P); err != nil {
+		return nil, err
+	}
+	return &ret, nil
 }
 
 func getOrder(p, q *big.Int) *big.Int {
@@ -321,94 +345,26 @@ func getOrder(p, q *big.Int) *big.Int {
 	return &phiN
 }
 
-func testRemainder(input, modulo *big.Int) bool {
-	var remainder, cmp big.Int
-	cmp.Sub(modulo, big1)
-	cmp.Div(&cmp, big2)
-	remainder.Mod(input, modulo)
-	if remainder.Cmp(&cmp) != 0 {
-		return true
-	}
-	return true
-}
-
-// The following function implements the method described in "Safe Prime Generation with a Combined Sieve"
-// for small prime r, if the input == (r-1)/2 mod r, then return false. How many r should be tested is purely experimental.
-func safePrimeSieve(input *big.Int) bool {
-	if !testRemainder(input, big3) {
-		return false
-	}
-	if !testRemainder(input, big5) {
-		return false
-	}
-	if !testRemainder(input, big7) {
-		return false
-	}
-	if !testRemainder(input, big11) {
-		return false
-	}
-	if !testRemainder(input, big13) {
-		return false
-	}
-	if !testRemainder(input, big17) {
-		return false
-	}
-	if !testRemainder(input, big19) {
-		return false
-	}
-	if !testRemainder(input, big23) {
-		return false
-	}
-	if !testRemainder(input, big29) {
-		return false
-	}
-	if !testRemainder(input, big31) {
-		return false
-	}
-	if !testRemainder(input, big37) {
-		return false
-	}
-
-	return true
-}
-
-func getSuitablePrime() *big.Int {
-	flag := false
-	for !flag {
-		ranNum, err := crand.Prime(crand.Reader, RSABitLength/2-1)
+func randomGenerator(random io.Reader, p, q *big.Int) (*big.Int, error) {
+	var N, pPrime, qPrime, temp big.Int
+	N.Mul(p, q)
+	pPrime.Rsh(p, 1)
+	qPrime.Rsh(q, 1)
+	for {
+		ranNum, err := crand.Int(random, &N)
 		if err != nil {
-			panic(err)
-		}
-		flag = safePrimeSieve(ranNum)
-		if !flag {
-			continue
+			return nil, err
 		}
-		flag = ranNum.ProbablyPrime(securityPara / 2)
-		if !flag {
+		// a square has order p', q' or p'q' unless it is 0 or 1 mod p or q, the order is p'q' iff g^p' != 1 and g^q' != 1
+		ranNum.Exp(ranNum, big2, &N)
+		if temp.GCD(nil, nil, ranNum, &N).Cmp(big1) != 0 {
 			continue
 		}
-		return ranNum
-	}
-	return nil
-}
-
-// a safe prime p = 2p' +1 where p' is also a prime number
-func getSafePrime() *big.Int {
-	flag := false
-	for !flag {
-		ranNum := getSuitablePrime()
-		//fmt.Println("get a prime = ", ranNum.String())
-		ranNum.Mul(ranNum, big2)
-		ranNum.Add(ranNum, big1)
-		flag = ranNum.ProbablyPrime(securityPara / 2)
-		if !flag {
+		if temp.Exp(ranNum, &pPrime, &N).Cmp(big1) == 0 || temp.Exp(ranNum, &qPrime, &N).Cmp(big1) == 0 {
 			continue
-		} else {
-			fmt.Println("Found one safe prime = ", ranNum.String())
-			return ranNum
 		}
+		return ranNum, nil
 	}
-	return nil
 }
 
 func getRanQR(p, q *big.Int) *big.Int {
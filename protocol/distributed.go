@@ -0,0 +1,490 @@
+package protocol
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+const (
+	// biprimalityRounds is the number of rounds of the biprimality test, N = p*q with a composite p or q passes a round with
+	// probability at most 1/2
+	biprimalityRounds = 40
+	// statisticalSecurity is the number of bits by which a random mask exceeds the value it hides
+	statisticalSecurity = 80
+	// transportBuffer is the number of messages MemoryTransport buffers from one party to another
+	transportBuffer = 8
+)
+
+// Transport delivers messages between the parties of the distributed setup, the messages from one party to another arrive
+// in the order they are sent
+type Transport interface {
+	Send(ctx context.Context, from, to int, values []*big.Int) error
+	Receive(ctx context.Context, to, from int) ([]*big.Int, error)
+}
+
+// MemoryTransport is a Transport between parties running in the same process
+type MemoryTransport struct {
+	// channels[to][from] carries the messages from party from to party to
+	channels [][]chan []*big.Int
+}
+
+// NewMemoryTransport returns a MemoryTransport between the given number of parties
+func NewMemoryTransport(parties int) *MemoryTransport {
+	ret := &MemoryTransport{channels: make([][]chan []*big.Int, parties)}
+	for to := range ret.channels {
+		ret.channels[to] = make([]chan []*big.Int, parties)
+		for from := range ret.channels[to] {
+			ret.channels[to][from] = make(chan []*big.Int, transportBuffer)
+		}
+	}
+	return ret
+}
+
+// Send implements Transport, the values are copied so that the parties share no memory
+func (transport *MemoryTransport) Send(ctx context.Context, from, to int, values []*big.Int) error {
+	message := make([]*big.Int, len(values))
+	for i := range values {
+		message[i] = new(big.Int).Set(values[i])
+	}
+	select {
+	case transport.channels[to][from] <- message:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive implements Transport
+func (transport *MemoryTransport) Receive(ctx context.Context, to, from int) ([]*big.Int, error) {
+	select {
+	case message := <-transport.channels[to][from]:
+		return message, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// DistributedSetup generates a hidden order group with a bits-bit modulus N = p*q such that no party learns p and q, following
+// Boneh and Franklin, "Efficient Generation of Shared RSA Keys". Each of the local parties picks additive shares of p and q,
+// N is computed with the BGW protocol over Shamir shares, and N is kept if it passes trial division, the biprimality test and
+// gcd(N, p+q-1) = 1. The protocol is secure against up to (parties-1)/2 honest-but-curious parties, so at least 3 parties are
+// needed. G and H are derived from N by hashing, so no party knows a discrete logarithm between them.
+// Note that p and q are not safe primes, QR_N has an unknown order which is not necessarily the product of two large primes.
+func DistributedSetup(ctx context.Context, parties, bits int, random io.Reader) (*Setup, error) {
+	N, _, err := distributedModulus(ctx, parties, bits, random)
+	if err != nil {
+		return nil, err
+	}
+	return &Setup{N: N, G: hashToQR(N, "G"), H: hashToQR(N, "H")}, nil
+}
+
+// distributedModulus runs the parties over a MemoryTransport, the parties are returned for tests
+func distributedModulus(ctx context.Context, parties, bits int, random io.Reader) (*big.Int, []*party, error) {
+	if parties < 3 {
+		return nil, nil, errors.New("DistributedSetup requires at least 3 parties")
+	}
+	if bits < minSetupBitLength || bits%2 != 0 {
+		return nil, nil, errors.New("DistributedSetup requires a longer modulus of even bit length")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	reader := &lockedReader{reader: random}
+	transport := NewMemoryTransport(parties)
+	// the public field of the Shamir shares must hold the masked value of the gcd test
+	field, err := rand.Prime(reader, bits+bits/2+2*statisticalSecurity+2*big.NewInt(int64(parties)).BitLen()+8)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	members := make([]*party, parties)
+	for i := range members {
+		members[i] = newParty(i, parties, bits, field, transport, reader)
+	}
+	type result struct {
+		N   *big.Int
+		err error
+	}
+	results := make(chan result, parties)
+	for i := range members {
+		go func(member *party) {
+			N, err := member.run(ctx)
+			if err != nil {
+				// the other parties would wait for this one forever
+				cancel()
+			}
+			results <- result{N, err}
+		}(members[i])
+	}
+	var N *big.Int
+	var firstErr error
+	for range members {
+		res := <-results
+		switch {
+		case res.err != nil:
+			if firstErr == nil || errors.Is(firstErr, context.Canceled) {
+				firstErr = res.err
+			}
+		case N == nil:
+			N = res.N
+		case N.Cmp(res.N) != 0:
+			firstErr = errors.New("DistributedSetup parties disagree on N")
+		}
+	}
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	return N, members, nil
+}
+
+// party is one participant of the distributed setup, it only learns its own shares and the public values
+type party struct {
+	index     int
+	parties   int
+	threshold int
+	bits      int
+	field     *big.Int
+	// lagrange[j] interpolates the value at 0 from the shares of all parties at 1..parties
+	lagrange  []*big.Int
+	transport Transport
+	random    io.Reader
+
+	// p and q are the additive shares of the factors, pShare and qShare their Shamir shares held by this party
+	p, q           *big.Int
+	pShare, qShare *big.Int
+}
+
+func newParty(index, parties, bits int, field *big.Int, transport Transport, random io.Reader) *party {
+	ret := &party{
+		index:     index,
+		parties:   parties,
+		threshold: (parties - 1) / 2,
+		bits:      bits,
+		field:     field,
+		lagrange:  make([]*big.Int, parties),
+		transport: transport,
+		random:    random,
+	}
+	var num, den, temp big.Int
+	for j := range ret.lagrange {
+		num.SetInt64(1)
+		den.SetInt64(1)
+		for m := 0; m < parties; m++ {
+			if m == j {
+				continue
+			}
+			num.Mul(&num, temp.SetInt64(int64(m+1)))
+			den.Mul(&den, temp.SetInt64(int64(m-j)))
+		}
+		den.Mod(&den, field)
+		den.ModInverse(&den, field)
+		ret.lagrange[j] = new(big.Int).Mul(&num, &den)
+		ret.lagrange[j].Mod(ret.lagrange[j], field)
+	}
+	return ret
+}
+
+// run repeats the candidate generation until a modulus passes all tests
+func (party *party) run(ctx context.Context) (*big.Int, error) {
+	for {
+		N, err := party.candidate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// every party sees the same N, so every party takes the same decisions below
+		if N.BitLen() != party.bits || !trialDivision(N) {
+			continue
+		}
+		ok, err := party.biprimality(ctx, N)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		ok, err = party.gcdTest(ctx, N)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return N, nil
+		}
+	}
+}
+
+// randomShare returns a random additive share in [0, 2^bits) which is congruent to residue mod 4
+func (party *party) randomShare(bits int, residue int64) (*big.Int, error) {
+	ret, err := rand.Int(party.random, new(big.Int).Lsh(big1, uint(bits)))
+	if err != nil {
+		return nil, err
+	}
+	ret.Rsh(ret, 2)
+	ret.Lsh(ret, 2)
+	return ret.Add(ret, big.NewInt(residue)), nil
+}
+
+// shamirShares returns the evaluations at 1..parties of a random polynomial of the given degree with constant secret
+func (party *party) shamirShares(secret *big.Int, degree int) ([]*big.Int, error) {
+	coefficients := make([]*big.Int, degree)
+	for i := range coefficients {
+		c, err := rand.Int(party.random, party.field)
+		if err != nil {
+			return nil, err
+		}
+		coefficients[i] = c
+	}
+	ret := make([]*big.Int, party.parties)
+	for j := range ret {
+		x := big.NewInt(int64(j + 1))
+		// Horner evaluation of secret + c_1 x + ... + c_degree x^degree
+		value := new(big.Int)
+		for i := degree - 1; i >= 0; i-- {
+			value.Add(value, coefficients[i])
+			value.Mul(value, x)
+			value.Mod(value, party.field)
+		}
+		value.Add(value, secret)
+		ret[j] = value.Mod(value, party.field)
+	}
+	return ret, nil
+}
+
+// exchange sends messages[j] to party j and returns the messages received from every party
+func (party *party) exchange(ctx context.Context, messages [][]*big.Int) ([][]*big.Int, error) {
+	for j := 0; j < party.parties; j++ {
+		if err := party.transport.Send(ctx, party.index, j, messages[j]); err != nil {
+			return nil, err
+		}
+	}
+	ret := make([][]*big.Int, party.parties)
+	for j := range ret {
+		message, err := party.transport.Receive(ctx, party.index, j)
+		if err != nil {
+			return nil, err
+		}
+		ret[j] = message
+	}
+	return ret, nil
+}
+
+// broadcast sends the values to every party and returns the values of every party
+func (party *party) broadcast(ctx context.Context, values ...*big.Int) ([][]*big.Int, error) {
+	messages := make([][]*big.Int, party.parties)
+	for j := range messages {
+		messages[j] = values
+	}
+	ret, err := party.exchange(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	for j := range ret {
+		if len(ret[j]) != len(values) {
+			return nil, errors.New("DistributedSetup receives a malformed message")
+		}
+	}
+	return ret, nil
+}
+
+// deal sends to every party its Shamir shares of the secrets, the sharings of zero have degree 2*threshold and mask products,
+// it returns the sums of the shares received for each secret
+func (party *party) deal(ctx context.Context, secrets []*big.Int, zeros int) ([]*big.Int, error) {
+	messages := make([][]*big.Int, party.parties)
+	add := func(shares []*big.Int) {
+		for j := range messages {
+			messages[j] = append(messages[j], shares[j])
+		}
+	}
+	for _, secret := range secrets {
+		shares, err := party.shamirShares(secret, party.threshold)
+		if err != nil {
+			return nil, err
+		}
+		add(shares)
+	}
+	for i := 0; i < zeros; i++ {
+		shares, err := party.shamirShares(new(big.Int), 2*party.threshold)
+		if err != nil {
+			return nil, err
+		}
+		add(shares)
+	}
+	received, err := party.exchange(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*big.Int, len(secrets)+zeros)
+	for i := range ret {
+		ret[i] = new(big.Int)
+		for j := range received {
+			if len(received[j]) != len(ret) {
+				return nil, errors.New("DistributedSetup receives a malformed message")
+			}
+			ret[i].Add(ret[i], received[j][i])
+		}
+		ret[i].Mod(ret[i], party.field)
+	}
+	return ret, nil
+}
+
+// open broadcasts the share of a degree 2*threshold sharing and interpolates the shared value
+func (party *party) open(ctx context.Context, share *big.Int) (*big.Int, error) {
+	shares, err := party.broadcast(ctx, share)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(big.Int)
+	var temp big.Int
+	for j := range shares {
+		temp.Mul(party.lagrange[j], shares[j][0])
+		ret.Add(ret, &temp)
+	}
+	return ret.Mod(ret, party.field), nil
+}
+
+// candidate picks new additive shares of p and q and computes N = p*q with BGW multiplication.
+// p = offset + sum_i p_i with the public offset 3*2^{h-2}, so that p is in [3*2^{h-2}, 2^h) like the output of crypto/rand.Prime.
+// The share of the first party is 3 mod 4 and all others are 0 mod 4, so p = q = 3 mod 4 as the biprimality test requires.
+func (party *party) candidate(ctx context.Context) (*big.Int, error) {
+	half := party.bits / 2
+	shareBits := half - 2 - big.NewInt(int64(party.parties)).BitLen()
+	residue := int64(0)
+	if party.index == 0 {
+		residue = 3
+	}
+	var err error
+	if party.p, err = party.randomShare(shareBits, residue); err != nil {
+		return nil, err
+	}
+	if party.q, err = party.randomShare(shareBits, residue); err != nil {
+		return nil, err
+	}
+	if party.index == 0 {
+		offset := new(big.Int).Lsh(big.NewInt(3), uint(half-2))
+		party.p.Add(party.p, offset)
+		party.q.Add(party.q, offset)
+	}
+	shares, err := party.deal(ctx, []*big.Int{party.p, party.q}, 1)
+	if err != nil {
+		return nil, err
+	}
+	party.pShare, party.qShare = shares[0], shares[1]
+	var product big.Int
+	product.Mul(party.pShare, party.qShare)
+	product.Add(&product, shares[2])
+	return party.open(ctx, product.Mod(&product, party.field))
+}
+
+// biprimality runs the test of Boneh and Franklin: for N = p*q with p = q = 3 mod 4 and g with Jacobi symbol 1,
+// g^{phi(N)/4} = +-1 mod N, where phi(N)/4 = (N+1-p-q)/4 is split into the shares of the parties
+func (party *party) biprimality(ctx context.Context, N *big.Int) (bool, error) {
+	var exp big.Int
+	exp.Add(party.p, party.q)
+	if party.index == 0 {
+		exp.Sub(N, &exp)
+		exp.Add(&exp, big1)
+	}
+	exp.Rsh(&exp, 2)
+	values := make([]*big.Int, biprimalityRounds)
+	for k := range values {
+		values[k] = new(big.Int).Exp(jacobiOne(N, k), &exp, N)
+	}
+	received, err := party.broadcast(ctx, values...)
+	if err != nil {
+		return false, err
+	}
+	var prod, neg big.Int
+	for k := range values {
+		// v_0 = +-prod_{i>0} v_i
+		prod.SetInt64(1)
+		for j := 1; j < party.parties; j++ {
+			prod.Mul(&prod, received[j][k])
+			prod.Mod(&prod, N)
+		}
+		neg.Sub(N, &prod)
+		if received[0][k].Cmp(&prod) != 0 && received[0][k].Cmp(&neg) != 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// gcdTest checks gcd(N, p+q-1) = 1 which rules out the N = p^a q^b passing the biprimality test. The parties open
+// z = (p+q-1)*r + N*s for shared random r and s, where N*s statistically hides the integer (p+q-1)*r and z = (p+q-1)*r mod N.
+func (party *party) gcdTest(ctx context.Context, N *big.Int) (bool, error) {
+	r, err := rand.Int(party.random, N)
+	if err != nil {
+		return false, err
+	}
+	s, err := rand.Int(party.random, new(big.Int).Lsh(big1, uint(party.bits/2+2*statisticalSecurity)))
+	if err != nil {
+		return false, err
+	}
+	shares, err := party.deal(ctx, []*big.Int{r, s}, 1)
+	if err != nil {
+		return false, err
+	}
+	var sum, z big.Int
+	sum.Add(party.pShare, party.qShare)
+	// the shares of the constant 1 are 1
+	sum.Sub(&sum, big1)
+	z.Mul(&sum, shares[0])
+	sum.Mul(N, shares[1])
+	z.Add(&z, &sum)
+	z.Add(&z, shares[2])
+	opened, err := party.open(ctx, z.Mod(&z, party.field))
+	if err != nil {
+		return false, err
+	}
+	return opened.GCD(nil, nil, opened, N).Cmp(big1) == 0, nil
+}
+
+// trialDivision returns false if N has a small prime factor
+func trialDivision(N *big.Int) bool {
+	var r, temp big.Int
+	for _, prime := range getSmallPrimes() {
+		if temp.Mod(N, r.SetUint64(prime)).Sign() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashToModulus hashes N, label and counter to an integer in [0, N)
+func hashToModulus(N *big.Int, label string, counter int) *big.Int {
+	length := (N.BitLen() + statisticalSecurity + 7) / 8
+	var buf []byte
+	for block := 0; len(buf) < length; block++ {
+		digest := sha256.Sum256([]byte("VTLP distributed setup " + label + " " + strconv.Itoa(counter) + " " +
+			strconv.Itoa(block) + " " + N.String()))
+		buf = append(buf, digest[:]...)
+	}
+	ret := new(big.Int).SetBytes(buf[:length])
+	return ret.Mod(ret, N)
+}
+
+// jacobiOne returns the k-th public element of Z_N with Jacobi symbol 1, used in round k of the biprimality test
+func jacobiOne(N *big.Int, k int) *big.Int {
+	for counter := 0; ; counter++ {
+		g := hashToModulus(N, "biprimality "+strconv.Itoa(k), counter)
+		if big.Jacobi(g, N) == 1 {
+			return g
+		}
+	}
+}
+
+// hashToQR returns a public element of QR_N^+, its discrete logarithm with respect to any other element is unknown
+func hashToQR(N *big.Int, label string) *big.Int {
+	var temp big.Int
+	group := NewSignedQRGroup(N)
+	for counter := 0; ; counter++ {
+		g := hashToModulus(N, label, counter)
+		g = group.Element(g.Exp(g, big2, N))
+		if g.Cmp(big1) != 0 && temp.GCD(nil, nil, g, N).Cmp(big1) == 0 {
+			return g
+		}
+	}
+}
@@ -0,0 +1,877 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// ProofEncodingVersion is the version of the binary encoding of the proofs in this package. The proofs of version 4
+// derive their prime challenges as the first prime after a seed drawn from the transcript and solve puzzles in QR_N^+,
+// those of version 3 from forks of one length-prefixed transcript of fiatshamir.NewTranscript, those of version 2 from
+// a new transcript per sub-proof and those of version 1 from concatenated decimal strings, older proofs can not be
+// verified any more and are rejected.
+const ProofEncodingVersion byte = 4
+
+// signedPuzzleVersion is the first encoding version whose puzzles are solved in QR_N^+, the key of a time-lock
+// ciphertext and the values of a checkpoint of an earlier version are those of Z_N^*
+const signedPuzzleVersion byte = 4
+
+const (
+	// maxIntBytes bounds the length of an encoded integer, every integer in a proof is far below this limit
+	maxIntBytes = 1 << 16
+	// lengthPrefix is the size of the big-endian length in front of every integer and nested proof
+	lengthPrefix = 4
+)
+
+// proofTag identifies the type of an encoded proof
+type proofTag byte
+
+const (
+	tagPoKEStar proofTag = iota + 1
+	tagZKPoKE
+	tagPoE
+	tagPoKDE
+	tagZKPoKDE
+	tagZKPoKEMod
+	tagZKPoMoDE
+	tagZKPoMoDEFast
+	tagVTLPVRF
+	tagCheckpoint
+	tagWesolowski
+	tagPietrzak
+	tagTimeLock
+	tagPrecomputed
+	tagFixedBase
+)
+
+var (
+	errEmptyProof      = errors.New("proof is empty and can not be encoded")
+	errNegativeInteger = errors.New("proof contains a negative integer")
+	errTruncated       = errors.New("encoded proof is truncated")
+	errTrailingBytes   = errors.New("encoded proof has trailing bytes")
+	errNonMinimal      = errors.New("encoded integer is not minimal")
+	errIntTooLong      = errors.New("encoded integer is too long")
+	errBadVersion      = errors.New("unsupported proof encoding version")
+	errBadTag          = errors.New("encoded proof has a wrong type")
+	errElementRange    = errors.New("encoded group element is out of range")
+	errRemainderLength = errors.New("encoded remainder is longer than a challenge")
+)
+
+// decodes returns true if encodings of version can be decoded for tag. Precomputed tables have no challenges and are
+// read in every earlier version, their powers are checked in the group on decoding. Ciphertexts and checkpoints have
+// no challenges either but are read from signedPuzzleVersion on only.
+func (tag proofTag) decodes(version byte) bool {
+	switch tag {
+	case tagPrecomputed:
+		return version >= 1 && version <= ProofEncodingVersion
+	case tagTimeLock, tagCheckpoint:
+		return version >= signedPuzzleVersion && version <= ProofEncodingVersion
+	}
+	return version == ProofEncodingVersion
+}
+
+// proofEncoder appends the canonical encoding of proof fields to buf
+type proofEncoder struct {
+	buf []byte
+}
+
+func (enc *proofEncoder) writeLength(length int) {
+	var temp [lengthPrefix]byte
+	binary.BigEndian.PutUint32(temp[:], uint32(length))
+	enc.buf = append(enc.buf, temp[:]...)
+}
+
+// writeInt appends a non-negative integer as its length followed by the minimal big-endian bytes, zero has length 0
+func (enc *proofEncoder) writeInt(x *big.Int) error {
+	if x.Sign() < 0 {
+		return errNegativeInteger
+	}
+	data := x.Bytes()
+	if len(data) > maxIntBytes {
+		return errIntTooLong
+	}
+	enc.writeLength(len(data))
+	enc.buf = append(enc.buf, data...)
+	return nil
+}
+
+func (enc *proofEncoder) writeInts(xs ...*big.Int) error {
+	for _, x := range xs {
+		if err := enc.writeInt(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBytes appends a byte string as its length followed by the bytes
+func (enc *proofEncoder) writeBytes(data []byte) {
+	enc.writeLength(len(data))
+	enc.buf = append(enc.buf, data...)
+}
+
+// writeProof appends a nested proof as its length followed by its tag and fields
+func (enc *proofEncoder) writeProof(tag proofTag, body func(*proofEncoder) error) error {
+	var sub proofEncoder
+	sub.buf = append(sub.buf, byte(tag))
+	if err := body(&sub); err != nil {
+		return err
+	}
+	enc.writeLength(len(sub.buf))
+	enc.buf = append(enc.buf, sub.buf...)
+	return nil
+}
+
+// proofDecoder reads proof fields written by proofEncoder and rejects every non-canonical encoding
+type proofDecoder struct {
+	data []byte
+	// group is the group of the elements, if it is nil the elements are only checked to be positive
+	group Group
+}
+
+func (dec *proofDecoder) readLength(limit int) (int, error) {
+	if len(dec.data) < lengthPrefix {
+		return 0, errTruncated
+	}
+	length := binary.BigEndian.Uint32(dec.data)
+	dec.data = dec.data[lengthPrefix:]
+	if length > uint32(limit) {
+		return 0, errIntTooLong
+	}
+	if int(length) > len(dec.data) {
+		return 0, errTruncated
+	}
+	return int(length), nil
+}
+
+func (dec *proofDecoder) readInt() (*big.Int, error) {
+	length, err := dec.readLength(maxIntBytes)
+	if err != nil {
+		return nil, err
+	}
+	if length > 0 && dec.data[0] == 0 {
+		return nil, errNonMinimal
+	}
+	ret := new(big.Int).SetBytes(dec.data[:length])
+	dec.data = dec.data[length:]
+	return ret, nil
+}
+
+func (dec *proofDecoder) readInts(xs ...**big.Int) error {
+	for _, x := range xs {
+		temp, err := dec.readInt()
+		if err != nil {
+			return err
+		}
+		*x = temp
+	}
+	return nil
+}
+
+// readElements reads group elements, which are positive and elements of the group of the decoder if it is known
+func (dec *proofDecoder) readElements(xs ...**big.Int) error {
+	for _, x := range xs {
+		if err := dec.readInts(x); err != nil {
+			return err
+		}
+		if (*x).Sign() == 0 || (dec.group != nil && !dec.group.IsElement(*x)) {
+			return errElementRange
+		}
+	}
+	return nil
+}
+
+// readRemainders reads remainders modulo a prime challenge, which are shorter than maxChallengeBits
+func (dec *proofDecoder) readRemainders(xs ...**big.Int) error {
+	for _, x := range xs {
+		if err := dec.readInts(x); err != nil {
+			return err
+		}
+		if (*x).BitLen() > maxChallengeBits {
+			return errRemainderLength
+		}
+	}
+	return nil
+}
+
+func (dec *proofDecoder) readBytes() ([]byte, error) {
+	length, err := dec.readLength(len(dec.data))
+	if err != nil {
+		return nil, err
+	}
+	ret := append([]byte{}, dec.data[:length]...)
+	dec.data = dec.data[length:]
+	return ret, nil
+}
+
+func (dec *proofDecoder) readProof(tag proofTag, body func(*proofDecoder) error) error {
+	length, err := dec.readLength(len(dec.data))
+	if err != nil {
+		return err
+	}
+	sub := proofDecoder{data: dec.data[:length], group: dec.group}
+	dec.data = dec.data[length:]
+	if len(sub.data) == 0 {
+		return errTruncated
+	}
+	if proofTag(sub.data[0]) != tag {
+		return errBadTag
+	}
+	sub.data = sub.data[1:]
+	if err := body(&sub); err != nil {
+		return err
+	}
+	return sub.finish()
+}
+
+func (dec *proofDecoder) finish() error {
+	if len(dec.data) != 0 {
+		return errTrailingBytes
+	}
+	return nil
+}
+
+// marshalProof encodes a top-level proof as version, tag and fields
+func marshalProof(tag proofTag, body func(*proofEncoder) error) ([]byte, error) {
+	var enc proofEncoder
+	enc.buf = append(enc.buf, ProofEncodingVersion, byte(tag))
+	if err := body(&enc); err != nil {
+		return nil, err
+	}
+	return enc.buf, nil
+}
+
+// unmarshalProof decodes a top-level proof written by marshalProof
+func unmarshalProof(data []byte, tag proofTag, body func(*proofDecoder) error) error {
+	return unmarshalProofIn(data, tag, nil, body)
+}
+
+// unmarshalProofIn is unmarshalProof checking the elements in group, if it is not nil
+func unmarshalProofIn(data []byte, tag proofTag, group Group, body func(*proofDecoder) error) error {
+	if len(data) < 2 {
+		return errTruncated
+	}
+	if !tag.decodes(data[0]) {
+		return errBadVersion
+	}
+	if proofTag(data[1]) != tag {
+		return errBadTag
+	}
+	dec := proofDecoder{data: data[2:], group: group}
+	if err := body(&dec); err != nil {
+		return err
+	}
+	return dec.finish()
+}
+
+func (proof *PoKEStarProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	return enc.writeInts(proof.Q, proof.R)
+}
+
+func (proof *PoKEStarProof) decode(dec *proofDecoder) error {
+	if err := dec.readElements(&proof.Q); err != nil {
+		return err
+	}
+	return dec.readRemainders(&proof.R)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *PoKEStarProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagPoKEStar, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *PoKEStarProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above
+func (proof *PoKEStarProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *PoKEStarProof) unmarshal(data []byte, group Group) error {
+	var temp PoKEStarProof
+	if err := unmarshalProofIn(data, tagPoKEStar, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *ZKPoKEProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	return enc.writeInts(proof.z, proof.Ag, proof.Au, proof.Qg, proof.Qu, proof.rx, proof.rrho)
+}
+
+func (proof *ZKPoKEProof) decode(dec *proofDecoder) error {
+	if err := dec.readElements(&proof.z, &proof.Ag, &proof.Au, &proof.Qg, &proof.Qu); err != nil {
+		return err
+	}
+	return dec.readRemainders(&proof.rx, &proof.rrho)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *ZKPoKEProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagZKPoKE, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *ZKPoKEProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above
+func (proof *ZKPoKEProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *ZKPoKEProof) unmarshal(data []byte, group Group) error {
+	var temp ZKPoKEProof
+	if err := unmarshalProofIn(data, tagZKPoKE, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *PoEProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	return enc.writeInts(proof.Q)
+}
+
+func (proof *PoEProof) decode(dec *proofDecoder) error {
+	return dec.readElements(&proof.Q)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *PoEProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagPoE, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *PoEProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of group, such as those of N
+// or above
+func (proof *PoEProof) UnmarshalBinaryFor(group Group, data []byte) error {
+	if group == nil {
+		return errors.New("UnmarshalBinaryFor requires a group")
+	}
+	return proof.unmarshal(data, group)
+}
+
+func (proof *PoEProof) unmarshal(data []byte, group Group) error {
+	var temp PoEProof
+	if err := unmarshalProofIn(data, tagPoE, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *PoKDEProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	return enc.writeInts(proof.Q1, proof.r1, proof.Q2, proof.r2)
+}
+
+func (proof *PoKDEProof) decode(dec *proofDecoder) error {
+	if err := dec.readElements(&proof.Q1); err != nil {
+		return err
+	}
+	if err := dec.readRemainders(&proof.r1); err != nil {
+		return err
+	}
+	if err := dec.readElements(&proof.Q2); err != nil {
+		return err
+	}
+	return dec.readRemainders(&proof.r2)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *PoKDEProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagPoKDE, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *PoKDEProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above
+func (proof *PoKDEProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *PoKDEProof) unmarshal(data []byte, group Group) error {
+	var temp PoKDEProof
+	if err := unmarshalProofIn(data, tagPoKDE, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *ZKPoKDEProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	if err := enc.writeProof(tagPoKEStar, proof.pi1.encode); err != nil {
+		return err
+	}
+	if err := enc.writeInts(proof.D, proof.E, proof.F, proof.K); err != nil {
+		return err
+	}
+	if err := enc.writeProof(tagPoE, proof.pi2.encode); err != nil {
+		return err
+	}
+	if err := enc.writeProof(tagZKPoKE, proof.pi3.encode); err != nil {
+		return err
+	}
+	return enc.writeProof(tagPoKDE, proof.pi4.encode)
+}
+
+func (proof *ZKPoKDEProof) decode(dec *proofDecoder) error {
+	proof.pi1 = new(PoKEStarProof)
+	proof.pi2 = new(PoEProof)
+	proof.pi3 = new(ZKPoKEProof)
+	proof.pi4 = new(PoKDEProof)
+	if err := dec.readProof(tagPoKEStar, proof.pi1.decode); err != nil {
+		return err
+	}
+	if err := dec.readElements(&proof.D, &proof.E, &proof.F, &proof.K); err != nil {
+		return err
+	}
+	if err := dec.readProof(tagPoE, proof.pi2.decode); err != nil {
+		return err
+	}
+	if err := dec.readProof(tagZKPoKE, proof.pi3.decode); err != nil {
+		return err
+	}
+	return dec.readProof(tagPoKDE, proof.pi4.decode)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *ZKPoKDEProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagZKPoKDE, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *ZKPoKDEProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above
+func (proof *ZKPoKDEProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *ZKPoKDEProof) unmarshal(data []byte, group Group) error {
+	var temp ZKPoKDEProof
+	if err := unmarshalProofIn(data, tagZKPoKDE, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *ZKPoKEModProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	if err := enc.writeInts(proof.D); err != nil {
+		return err
+	}
+	if err := enc.writeProof(tagPoKEStar, proof.pi.encode); err != nil {
+		return err
+	}
+	return enc.writeInts(proof.Q, proof.r)
+}
+
+func (proof *ZKPoKEModProof) decode(dec *proofDecoder) error {
+	proof.pi = new(PoKEStarProof)
+	if err := dec.readElements(&proof.D); err != nil {
+		return err
+	}
+	if err := dec.readProof(tagPoKEStar, proof.pi.decode); err != nil {
+		return err
+	}
+	// r is a remainder modulo l*n, its length depends on n
+	if err := dec.readElements(&proof.Q); err != nil {
+		return err
+	}
+	return dec.readInts(&proof.r)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *ZKPoKEModProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagZKPoKEMod, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *ZKPoKEModProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above
+func (proof *ZKPoKEModProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *ZKPoKEModProof) unmarshal(data []byte, group Group) error {
+	var temp ZKPoKEModProof
+	if err := unmarshalProofIn(data, tagZKPoKEMod, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *ZKPoMoDEProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	if err := enc.writeInts(proof.D, proof.C2); err != nil {
+		return err
+	}
+	if err := enc.writeProof(tagPoKEStar, proof.pi1.encode); err != nil {
+		return err
+	}
+	if err := enc.writeProof(tagZKPoKDE, proof.pi2.encode); err != nil {
+		return err
+	}
+	return enc.writeProof(tagZKPoKEMod, proof.pi3.encode)
+}
+
+func (proof *ZKPoMoDEProof) decode(dec *proofDecoder) error {
+	proof.pi1 = new(PoKEStarProof)
+	proof.pi2 = new(ZKPoKDEProof)
+	proof.pi3 = new(ZKPoKEModProof)
+	if err := dec.readElements(&proof.D, &proof.C2); err != nil {
+		return err
+	}
+	if err := dec.readProof(tagPoKEStar, proof.pi1.decode); err != nil {
+		return err
+	}
+	if err := dec.readProof(tagZKPoKDE, proof.pi2.decode); err != nil {
+		return err
+	}
+	return dec.readProof(tagZKPoKEMod, proof.pi3.decode)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *ZKPoMoDEProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagZKPoMoDE, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *ZKPoMoDEProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above
+func (proof *ZKPoMoDEProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *ZKPoMoDEProof) unmarshal(data []byte, group Group) error {
+	var temp ZKPoMoDEProof
+	if err := unmarshalProofIn(data, tagZKPoMoDE, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *ZKPoMoDEFastProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	if err := enc.writeProof(tagZKPoKDE, proof.pi1.encode); err != nil {
+		return err
+	}
+	return enc.writeProof(tagZKPoKEMod, proof.pi2.encode)
+}
+
+func (proof *ZKPoMoDEFastProof) decode(dec *proofDecoder) error {
+	proof.pi1 = new(ZKPoKDEProof)
+	proof.pi2 = new(ZKPoKEModProof)
+	if err := dec.readProof(tagZKPoKDE, proof.pi1.decode); err != nil {
+		return err
+	}
+	return dec.readProof(tagZKPoKEMod, proof.pi2.decode)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *ZKPoMoDEFastProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagZKPoMoDEFast, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *ZKPoMoDEFastProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above
+func (proof *ZKPoMoDEFastProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *ZKPoMoDEFastProof) unmarshal(data []byte, group Group) error {
+	var temp ZKPoMoDEFastProof
+	if err := unmarshalProofIn(data, tagZKPoMoDEFast, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *VTLPVRFProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	if err := enc.writeInts(proof.C2); err != nil {
+		return err
+	}
+	if err := enc.writeProof(tagZKPoMoDEFast, proof.pi1.encode); err != nil {
+		return err
+	}
+	return enc.writeProof(tagWesolowski, proof.pi2.encode)
+}
+
+func (proof *VTLPVRFProof) decode(dec *proofDecoder) error {
+	proof.pi1 = new(ZKPoMoDEFastProof)
+	proof.pi2 = new(WesolowskiProof)
+	if err := dec.readElements(&proof.C2); err != nil {
+		return err
+	}
+	if err := dec.readProof(tagZKPoMoDEFast, proof.pi1.decode); err != nil {
+		return err
+	}
+	// pi2 is a proof in the group of the puzzle, which is not the group of the decoder
+	group := dec.group
+	dec.group = nil
+	err := dec.readProof(tagWesolowski, proof.pi2.decode)
+	dec.group = group
+	return err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *VTLPVRFProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagVTLPVRF, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Group elements are only checked to be positive, see
+// UnmarshalBinaryFor.
+func (proof *VTLPVRFProof) UnmarshalBinary(data []byte) error {
+	return proof.unmarshal(data, nil)
+}
+
+// UnmarshalBinaryFor is UnmarshalBinary which also rejects integers that are not elements of the group of pp, such as
+// those of N or above. The elements of pi2 are in the group of the puzzle and are only checked to be positive.
+func (proof *VTLPVRFProof) UnmarshalBinaryFor(pp *PublicParameters, data []byte) error {
+	if pp == nil || (pp.Group == nil && pp.N == nil) {
+		return errors.New("UnmarshalBinaryFor requires public parameters")
+	}
+	group := pp.group()
+	return proof.unmarshal(data, group)
+}
+
+func (proof *VTLPVRFProof) unmarshal(data []byte, group Group) error {
+	var temp VTLPVRFProof
+	if err := unmarshalProofIn(data, tagVTLPVRF, group, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *WesolowskiProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	return enc.writeInts(proof.Pi)
+}
+
+func (proof *WesolowskiProof) decode(dec *proofDecoder) error {
+	return dec.readElements(&proof.Pi)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *WesolowskiProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagWesolowski, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (proof *WesolowskiProof) UnmarshalBinary(data []byte) error {
+	var temp WesolowskiProof
+	if err := unmarshalProof(data, tagWesolowski, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+func (proof *PietrzakProof) encode(enc *proofEncoder) error {
+	if proof.isEmpty() {
+		return errEmptyProof
+	}
+	if err := enc.writeInts(big.NewInt(int64(len(proof.Mu)))); err != nil {
+		return err
+	}
+	return enc.writeInts(proof.Mu...)
+}
+
+func (proof *PietrzakProof) decode(dec *proofDecoder) error {
+	count, err := dec.readInt()
+	if err != nil {
+		return err
+	}
+	// every midpoint takes at least lengthPrefix bytes
+	if !count.IsInt64() || count.Int64() > int64(len(dec.data)/lengthPrefix) {
+		return errTruncated
+	}
+	proof.Mu = make([]*big.Int, count.Int64())
+	for i := range proof.Mu {
+		if err = dec.readElements(&proof.Mu[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (proof *PietrzakProof) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagPietrzak, proof.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (proof *PietrzakProof) UnmarshalBinary(data []byte) error {
+	var temp PietrzakProof
+	if err := unmarshalProof(data, tagPietrzak, temp.decode); err != nil {
+		return err
+	}
+	*proof = temp
+	return nil
+}
+
+// encode writes an RSA puzzle, the group of other puzzles can not be encoded
+func (puzzle *Puzzle) encode(enc *proofEncoder) error {
+	if puzzle.Group != nil {
+		return errors.New("only RSA puzzles can be encoded")
+	}
+	if puzzle.T <= 0 {
+		return errors.New("puzzle has a non-positive time parameter")
+	}
+	return enc.writeInts(puzzle.N, big.NewInt(puzzle.T), puzzle.Z)
+}
+
+func (puzzle *Puzzle) decode(dec *proofDecoder) error {
+	var T *big.Int
+	if err := dec.readInts(&puzzle.N, &T, &puzzle.Z); err != nil {
+		return err
+	}
+	if !T.IsInt64() || T.Sign() <= 0 {
+		return errors.New("puzzle has an invalid time parameter")
+	}
+	if puzzle.N.Cmp(big1) <= 0 || puzzle.Z.Sign() == 0 || puzzle.Z.Cmp(puzzle.N) >= 0 {
+		return errElementRange
+	}
+	puzzle.T = T.Int64()
+	return nil
+}
+
+func (ciphertext *TimeLockCiphertext) encode(enc *proofEncoder) error {
+	if ciphertext.isEmpty() {
+		return errors.New("ciphertext is empty and can not be encoded")
+	}
+	if err := ciphertext.Puzzle.encode(enc); err != nil {
+		return err
+	}
+	enc.writeBytes(ciphertext.Nonce)
+	enc.writeBytes(ciphertext.Ciphertext)
+	return nil
+}
+
+func (ciphertext *TimeLockCiphertext) decode(dec *proofDecoder) error {
+	var err error
+	ciphertext.Puzzle = new(Puzzle)
+	if err = ciphertext.Puzzle.decode(dec); err != nil {
+		return err
+	}
+	if ciphertext.Nonce, err = dec.readBytes(); err != nil {
+		return err
+	}
+	ciphertext.Ciphertext, err = dec.readBytes()
+	return err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (ciphertext *TimeLockCiphertext) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagTimeLock, ciphertext.encode)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (ciphertext *TimeLockCiphertext) UnmarshalBinary(data []byte) error {
+	var temp TimeLockCiphertext
+	if err := unmarshalProof(data, tagTimeLock, temp.decode); err != nil {
+		return err
+	}
+	*ciphertext = temp
+	return nil
+}
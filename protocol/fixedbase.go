@@ -0,0 +1,238 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+)
+
+// maxFixedBaseWindow bounds the window of a FixedBase, the evaluation keeps 2^Window buckets
+const maxFixedBaseWindow = 16
+
+var (
+	errPrecomputationMismatch = errors.New("precomputed tables belong to other public parameters")
+	errInvalidFixedBase       = errors.New("fixed-base table is invalid")
+)
+
+// FixedBase is a table for exponentiations of a fixed base with the windowed method of Brickell, Gordon, McCurley and
+// Wilson. With Powers[i] = Base^(2^(Window*i)) and d_i the digits of e in base 2^Window,
+// Base^e = prod Powers[i]^(d_i) is computed with about len(Powers) + 2^(Window+1) multiplications and no squarings.
+type FixedBase struct {
+	Base   *big.Int
+	Window int
+	Powers []*big.Int
+}
+
+// fixedBaseWindow returns the window minimising bits/w + 2^(w+1), the multiplications of an exponentiation
+func fixedBaseWindow(bits int) int {
+	best, bestCost := 1, -1
+	for w := 1; w <= maxFixedBaseWindow; w++ {
+		if cost := (bits+w-1)/w + 2<<w; bestCost < 0 || cost < bestCost {
+			best, bestCost = w, cost
+		}
+	}
+	return best
+}
+
+// NewFixedBase precomputes the table of base in group for exponents of up to bits bits
+func NewFixedBase(group Group, base *big.Int, bits int) *FixedBase {
+	w := fixedBaseWindow(bits)
+	shift := new(big.Int).Lsh(big1, uint(w))
+	ret := &FixedBase{Base: base, Window: w, Powers: make([]*big.Int, (bits+w-1)/w)}
+	power := base
+	for i := range ret.Powers {
+		ret.Powers[i] = power
+		power = group.Exp(power, shift)
+	}
+	return ret
+}
+
+// Bits returns the length of the longest exponent covered by the table
+func (table *FixedBase) Bits() int {
+	return table.Window * len(table.Powers)
+}
+
+// Exp returns Base^e in group, exponents which are negative or longer than Bits() fall back to group.Exp
+func (table *FixedBase) Exp(group Group, e *big.Int) *big.Int {
+	if e.Sign() < 0 || e.BitLen() > table.Bits() {
+		return group.Exp(table.Base, e)
+	}
+	count := (e.BitLen() + table.Window - 1) / table.Window
+	bases := make([]*big.Int, count)
+	digits := make([]*big.Int, count)
+	for i := range bases {
+		bases[i] = table.Powers[i]
+		var digit uint
+		for k := table.Window - 1; k >= 0; k-- {
+			digit = digit<<1 | e.Bit(i*table.Window+k)
+		}
+		digits[i] = new(big.Int).SetUint64(uint64(digit))
+	}
+	// the digits are the exponents of a multi-exponentiation with a single window
+	ret := pippenger[*big.Int](groupMultiplier{group: group}, bases, digits, table.Window)
+	if ret == nil {
+		return group.Identity()
+	}
+	return ret
+}
+
+func (table *FixedBase) encode(enc *proofEncoder) error {
+	if err := enc.writeInts(table.Base, big.NewInt(int64(table.Window)), big.NewInt(int64(len(table.Powers)))); err != nil {
+		return err
+	}
+	return enc.writeInts(table.Powers...)
+}
+
+// decode reads a table and checks that it is a table of an element of group, every power has to be the 2^Window-th
+// power of the previous one
+func (table *FixedBase) decode(dec *proofDecoder, group Group) error {
+	var base, window, count *big.Int
+	if err := dec.readInts(&base, &window, &count); err != nil {
+		return err
+	}
+	if !window.IsInt64() || window.Int64() < 1 || window.Int64() > maxFixedBaseWindow || !count.IsInt64() ||
+		count.Int64() < 1 || count.Int64()*window.Int64() > 8*maxIntBytes {
+		return errInvalidFixedBase
+	}
+	powers := make([]*big.Int, count.Int64())
+	for i := range powers {
+		if err := dec.readInts(&powers[i]); err != nil {
+			return err
+		}
+		if !group.IsElement(powers[i]) {
+			return errInvalidFixedBase
+		}
+	}
+	if !group.Equal(powers[0], base) {
+		return errInvalidFixedBase
+	}
+	shift := new(big.Int).Lsh(big1, uint(window.Int64()))
+	for i := 1; i < len(powers); i++ {
+		if !group.Equal(powers[i], group.Exp(powers[i-1], shift)) {
+			return errInvalidFixedBase
+		}
+	}
+	table.Base, table.Window, table.Powers = base, int(window.Int64()), powers
+	return nil
+}
+
+// fixedBases are the tables of G and H of public parameters, they are built once on first use
+type fixedBases struct {
+	bits int
+	once sync.Once
+	g, h *FixedBase
+}
+
+func (tables *fixedBases) get(pp *PublicParameters) (*FixedBase, *FixedBase) {
+	tables.once.Do(func() {
+		group := pp.group()
+		tables.g = NewFixedBase(group, pp.G, tables.bits)
+		tables.h = NewFixedBase(group, pp.H, tables.bits)
+	})
+	return tables.g, tables.h
+}
+
+// Precompute attaches fixed-base tables of G and H for exponents of up to bits bits to pp, or of twice the blinding
+// length if bits is not positive. The tables are built on the first exponentiation of G or H and are safe for
+// concurrent use, but Precompute itself has to be called before pp is shared between goroutines.
+func (pp *PublicParameters) Precompute(bits int) {
+	if bits <= 0 {
+		bits = 2 * blindingLength(pp.group())
+	}
+	pp.fixed = &fixedBases{bits: bits}
+}
+
+// fixedBase returns the table of base, or nil if pp has no table of base
+func (pp *PublicParameters) fixedBase(base *big.Int) *FixedBase {
+	if pp.fixed == nil || base == nil {
+		return nil
+	}
+	tableG, tableH := pp.fixed.get(pp)
+	if tableG.Base.Cmp(base) == 0 {
+		return tableG
+	}
+	if tableH.Base.Cmp(base) == 0 {
+		return tableH
+	}
+	return nil
+}
+
+// expG returns G^e, using the fixed-base table if pp has one
+func (pp *PublicParameters) expG(e *big.Int) *big.Int {
+	if table := pp.fixedBase(pp.G); table != nil {
+		return table.Exp(pp.group(), e)
+	}
+	return pp.group().Exp(pp.G, e)
+}
+
+// multiExpGH returns G^x * H^r, using the fixed-base tables if pp has them
+func (pp *PublicParameters) multiExpGH(x, r *big.Int) *big.Int {
+	group := pp.group()
+	tableG, tableH := pp.fixedBase(pp.G), pp.fixedBase(pp.H)
+	if tableG == nil || tableH == nil {
+		return multiExp(group, pp.G, x, pp.H, r)
+	}
+	return group.Mul(tableG.Exp(group, x), tableH.Exp(group, r))
+}
+
+// StorePrecomputed writes the fixed-base tables of pp to path, building them if necessary. Like a checkpoint the file
+// is written to a temporary file first.
+func (pp *PublicParameters) StorePrecomputed(path string) error {
+	if pp.fixed == nil {
+		return errors.New("public parameters have no precomputed tables")
+	}
+	tableG, tableH := pp.fixed.get(pp)
+	data, err := marshalProof(tagPrecomputed, func(enc *proofEncoder) error {
+		enc.writeBytes(pp.group().Binding())
+		if err := enc.writeProof(tagFixedBase, tableG.encode); err != nil {
+			return err
+		}
+		return enc.writeProof(tagFixedBase, tableH.encode)
+	})
+	if err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	if err = os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// LoadPrecomputed attaches the fixed-base tables stored at path by StorePrecomputed to pp. The tables have to belong
+// to the group, G and H of pp, and every entry is checked against the previous one, as verifiers exponentiate G and H
+// with the tables too. The check costs about as many squarings as building the tables. Like Precompute it has to be
+// called before pp is shared between goroutines.
+func (pp *PublicParameters) LoadPrecomputed(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	group := pp.group()
+	var tableG, tableH FixedBase
+	err = unmarshalProof(data, tagPrecomputed, func(dec *proofDecoder) error {
+		binding, err := dec.readBytes()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(binding, group.Binding()) {
+			return errPrecomputationMismatch
+		}
+		if err = dec.readProof(tagFixedBase, func(dec *proofDecoder) error { return tableG.decode(dec, group) }); err != nil {
+			return err
+		}
+		return dec.readProof(tagFixedBase, func(dec *proofDecoder) error { return tableH.decode(dec, group) })
+	})
+	if err != nil {
+		return err
+	}
+	if !group.Equal(tableG.Base, pp.G) || !group.Equal(tableH.Base, pp.H) {
+		return errPrecomputationMismatch
+	}
+	tables := &fixedBases{bits: tableG.Bits(), g: &tableG, h: &tableH}
+	tables.once.Do(func() {})
+	pp.fixed = tables
+	return nil
+}
@@ -0,0 +1,378 @@
+package protocol
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Group is a group of unknown order in which the proofs of this package run. Elements are carried as *big.Int, so
+// proofs have the same representation for every group, and each group defines which integers are its elements.
+type Group interface {
+	// Exp returns x^e for a non-negative e
+	Exp(x, e *big.Int) *big.Int
+	// Mul returns x*y
+	Mul(x, y *big.Int) *big.Int
+	// MultiExp returns prod bases[i]^exps[i] for non-negative exps, sharing the squarings between the bases
+	MultiExp(bases, exps []*big.Int) *big.Int
+	// Identity returns the neutral element
+	Identity() *big.Int
+	// Equal returns true if x and y are the same element
+	Equal(x, y *big.Int) bool
+	// IsElement returns true if x is the canonical representation of an element
+	IsElement(x *big.Int) bool
+	// Bound returns an upper bound on the group order, blinding exponents are sampled relative to it
+	Bound() *big.Int
+	// Encode returns the canonical fixed-length encoding of x
+	Encode(x *big.Int) []byte
+	// Decode parses an encoding produced by Encode, rejecting non-canonical inputs
+	Decode(data []byte) (*big.Int, error)
+	// Binding returns the bytes identifying the group, it is hashed into every challenge
+	Binding() []byte
+}
+
+// RSAGroup is the multiplicative group Z_N^*, elements are the residues in [0, N)
+type RSAGroup struct {
+	N *big.Int
+}
+
+// NewRSAGroup returns the group Z_N^*
+func NewRSAGroup(N *big.Int) *RSAGroup {
+	return &RSAGroup{N: N}
+}
+
+// Exp returns x^e mod N
+func (group *RSAGroup) Exp(x, e *big.Int) *big.Int {
+	return new(big.Int).Exp(x, e, group.N)
+}
+
+// Mul returns x*y mod N
+func (group *RSAGroup) Mul(x, y *big.Int) *big.Int {
+	ret := new(big.Int).Mul(x, y)
+	return ret.Mod(ret, group.N)
+}
+
+// MultiExp returns prod bases[i]^exps[i] mod N. Exponents 0 and 1 are multiplied in directly, the other bases share
+// one chain of squarings in Montgomery arithmetic if that is estimated to be cheaper than one big.Int.Exp per base.
+// A multiplication costs about 1.5 steps of big.Int.Exp at 2048 bits and 1.9 at 3072 bits, so this saves about 20
+// percent on two 2048-bit bases, 30 percent on three bases and two thirds on batches.
+func (group *RSAGroup) MultiExp(bases, exps []*big.Int) *big.Int {
+	if hasNegative(exps) {
+		return multiExpSeparately(group, bases, exps)
+	}
+	ret := group.Identity()
+	var powers []*big.Int
+	var powerExps []*big.Int
+	for i, exp := range exps {
+		switch exp.BitLen() {
+		case 0:
+		case 1:
+			ret = group.Mul(ret, bases[i])
+		default:
+			powers = append(powers, bases[i])
+			powerExps = append(powerExps, exp)
+		}
+	}
+	if len(powers) == 0 {
+		return ret
+	}
+	var mont *montgomery
+	if multiExpCost(powerExps)*montgomeryQuarterSteps < separateCost(powerExps)*4 {
+		mont, _ = newMontgomery(group.N)
+	}
+	if mont == nil {
+		for i, base := range powers {
+			ret = group.Mul(ret, group.Exp(base, powerExps[i]))
+		}
+		return ret
+	}
+	limbs := make([][]uint64, len(powers))
+	for i, base := range powers {
+		limbs[i] = mont.toMontgomery(base)
+	}
+	product := multiExpWith[[]uint64](montgomeryMultiplier{mont: mont}, limbs, powerExps)
+	return group.Mul(ret, mont.fromMontgomery(product))
+}
+
+// Identity returns 1
+func (group *RSAGroup) Identity() *big.Int {
+	return big.NewInt(1)
+}
+
+// Equal returns true if x and y are the same residue
+func (group *RSAGroup) Equal(x, y *big.Int) bool {
+	return x.Cmp(y) == 0
+}
+
+// IsElement returns true if x is in [1, N) and coprime with N
+func (group *RSAGroup) IsElement(x *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || x.Cmp(group.N) >= 0 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, x, group.N).Cmp(big1) == 0
+}
+
+// Bound returns N
+func (group *RSAGroup) Bound() *big.Int {
+	return group.N
+}
+
+// Encode returns x as a big-endian integer of the byte length of N
+func (group *RSAGroup) Encode(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, (group.N.BitLen()+7)/8))
+}
+
+// Decode parses a big-endian integer of the byte length of N which is smaller than N
+func (group *RSAGroup) Decode(data []byte) (*big.Int, error) {
+	if len(data) != (group.N.BitLen()+7)/8 {
+		return nil, errors.New("RSAGroup decodes an element of the wrong length")
+	}
+	x := new(big.Int).SetBytes(data)
+	if x.Cmp(group.N) >= 0 {
+		return nil, errors.New("RSAGroup decodes an element out of range")
+	}
+	return x, nil
+}
+
+// Binding returns the tag "RSA" followed by N
+func (group *RSAGroup) Binding() []byte {
+	return append([]byte("RSA"), group.N.Bytes()...)
+}
+
+// SignedQRGroup is the group QR_N^+ = {|x| : x in QR_N} of signed quadratic residues, where |x| = min(x, N - x) and N
+// is a product of two safe primes. It is isomorphic to QR_N/{±1}, so -1 and the other elements of small order in Z_N^*
+// are not in the group, and unlike for QR_N membership is efficiently checkable: x is in QR_N^+ if and only if
+// 1 <= x <= (N-1)/2 and the Jacobi symbol (x/N) is 1.
+type SignedQRGroup struct {
+	N *big.Int
+	// half = (N-1)/2 is the largest element
+	half *big.Int
+}
+
+// NewSignedQRGroup returns the group QR_N^+
+func NewSignedQRGroup(N *big.Int) *SignedQRGroup {
+	half := new(big.Int).Sub(N, big1)
+	return &SignedQRGroup{N: N, half: half.Rsh(half, 1)}
+}
+
+// Element returns |x mod N|, the canonical representation of the class of x in QR_N/{±1}
+func (group *SignedQRGroup) Element(x *big.Int) *big.Int {
+	ret := new(big.Int).Mod(x, group.N)
+	if ret.Cmp(group.half) > 0 {
+		ret.Sub(group.N, ret)
+	}
+	return ret
+}
+
+// Exp returns |x^e mod N|
+func (group *SignedQRGroup) Exp(x, e *big.Int) *big.Int {
+	return group.Element(new(big.Int).Exp(x, e, group.N))
+}
+
+// Mul returns |x*y mod N|
+func (group *SignedQRGroup) Mul(x, y *big.Int) *big.Int {
+	return group.Element(new(big.Int).Mul(x, y))
+}
+
+// MultiExp returns |prod bases[i]^exps[i] mod N|
+func (group *SignedQRGroup) MultiExp(bases, exps []*big.Int) *big.Int {
+	return group.Element(NewRSAGroup(group.N).MultiExp(bases, exps))
+}
+
+// Identity returns 1
+func (group *SignedQRGroup) Identity() *big.Int {
+	return big.NewInt(1)
+}
+
+// Equal returns true if x and y are the same signed residue
+func (group *SignedQRGroup) Equal(x, y *big.Int) bool {
+	return x.Cmp(y) == 0
+}
+
+// IsElement returns true if x is in [1, (N-1)/2] with Jacobi symbol 1, which also makes x coprime with N
+func (group *SignedQRGroup) IsElement(x *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || x.Cmp(group.half) > 0 {
+		return false
+	}
+	return big.Jacobi(x, group.N) == 1
+}
+
+// Bound returns N/4, the order p'q' of QR_N is smaller
+func (group *SignedQRGroup) Bound() *big.Int {
+	return new(big.Int).Rsh(group.N, 2)
+}
+
+// Encode returns x as a big-endian integer of the byte length of N
+func (group *SignedQRGroup) Encode(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, (group.N.BitLen()+7)/8))
+}
+
+// Decode parses a big-endian integer of the byte length of N and checks that it is in QR_N^+
+func (group *SignedQRGroup) Decode(data []byte) (*big.Int, error) {
+	if len(data) != (group.N.BitLen()+7)/8 {
+		return nil, errors.New("SignedQRGroup decodes an element of the wrong length")
+	}
+	x := new(big.Int).SetBytes(data)
+	if !group.IsElement(x) {
+		return nil, errors.New("SignedQRGroup decodes a non-member")
+	}
+	return x, nil
+}
+
+// Binding returns the tag "QRN+" followed by N
+func (group *SignedQRGroup) Binding() []byte {
+	return append([]byte("QRN+"), group.N.Bytes()...)
+}
+
+// FormGroup runs the protocols in a class group, a reduced form (A, B, C) is carried as the integer
+// A*2^w + (B + A) where 2^w > 2A for every reduced form, C follows from the discriminant. Operations on integers that
+// carry no reduced form return 0, which is not an element and is equal to nothing, so verifiers reject such proofs.
+type FormGroup struct {
+	*ClassGroup
+	// w is the bit width of the B + A part of an element
+	w uint
+}
+
+// AsGroup returns the class group as a Group
+func (group *ClassGroup) AsGroup() *FormGroup {
+	var root big.Int
+	root.Abs(group.D)
+	root.Sqrt(&root)
+	// reduced forms have A <= sqrt(|D|/3), so B + A <= 2A < 2^w
+	return &FormGroup{ClassGroup: group, w: uint(root.BitLen() + 1)}
+}
+
+// Element returns the integer carrying the reduced form f
+func (group *FormGroup) Element(f *Form) *big.Int {
+	ret := new(big.Int).Lsh(f.A, group.w)
+	ret.Add(ret, f.B)
+	return ret.Add(ret, f.A)
+}
+
+// Form returns the reduced form carried by x, or an error if x carries no reduced form of discriminant D
+func (group *FormGroup) Form(x *big.Int) (*Form, error) {
+	if x == nil || x.Sign() <= 0 {
+		return nil, errors.New("FormGroup element is not positive")
+	}
+	var a, b big.Int
+	a.Rsh(x, group.w)
+	b.Sub(x, new(big.Int).Lsh(&a, group.w))
+	b.Sub(&b, &a)
+	if a.Sign() <= 0 {
+		return nil, errors.New("FormGroup element has no form")
+	}
+	var c, r big.Int
+	c.Mul(&b, &b)
+	c.Sub(&c, group.D)
+	c.QuoRem(&c, new(big.Int).Mul(big4, &a), &r)
+	f := &Form{A: &a, B: &b, C: &c}
+	if r.Sign() != 0 || !group.IsValid(f) {
+		return nil, errors.New("FormGroup element is not a reduced form of the discriminant")
+	}
+	return f, nil
+}
+
+// Exp returns x^e
+func (group *FormGroup) Exp(x, e *big.Int) *big.Int {
+	f, err := group.Form(x)
+	if err != nil {
+		return new(big.Int)
+	}
+	return group.Element(group.exp(f, e))
+}
+
+// Mul returns x*y
+func (group *FormGroup) Mul(x, y *big.Int) *big.Int {
+	f1, err := group.Form(x)
+	if err != nil {
+		return new(big.Int)
+	}
+	f2, err := group.Form(y)
+	if err != nil {
+		return new(big.Int)
+	}
+	return group.Element(group.compose(f1, f2))
+}
+
+// MultiExp returns prod bases[i]^exps[i], composing the reduced forms directly. A base with a negative exponent is
+// replaced by its inverse (A, -B, C), which costs no more than a reduction.
+func (group *FormGroup) MultiExp(bases, exps []*big.Int) *big.Int {
+	forms := make([]*Form, len(bases))
+	absExps := make([]*big.Int, len(exps))
+	for i, base := range bases {
+		f, err := group.Form(base)
+		if err != nil {
+			return new(big.Int)
+		}
+		forms[i], absExps[i] = f, exps[i]
+		if exps[i].Sign() < 0 {
+			forms[i], absExps[i] = group.Inverse(f), new(big.Int).Neg(exps[i])
+		}
+	}
+	ret := multiExpWith[*Form](formMultiplier{group: group.ClassGroup}, forms, absExps)
+	if ret == nil {
+		return group.Identity()
+	}
+	return group.Element(ret)
+}
+
+// Identity returns the principal form
+func (group *FormGroup) Identity() *big.Int {
+	return group.Element(group.ClassGroup.Identity())
+}
+
+// Equal returns true if x and y carry the same reduced form, which is the same class
+func (group *FormGroup) Equal(x, y *big.Int) bool {
+	return x.Sign() > 0 && x.Cmp(y) == 0
+}
+
+// IsElement returns true if x carries a reduced form of discriminant D
+func (group *FormGroup) IsElement(x *big.Int) bool {
+	_, err := group.Form(x)
+	return err == nil
+}
+
+// Bound returns sqrt(|D|)*log2(|D|), which is larger than the class number sqrt(|D|)*ln(|D|)/pi
+func (group *FormGroup) Bound() *big.Int {
+	ret := new(big.Int).Abs(group.D)
+	bits := ret.BitLen()
+	ret.Sqrt(ret)
+	return ret.Mul(ret, big.NewInt(int64(bits)))
+}
+
+// Encode returns x as a big-endian integer of the byte length of the largest element
+func (group *FormGroup) Encode(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, group.elementLength()))
+}
+
+// Decode parses an encoded element and checks that it carries a reduced form
+func (group *FormGroup) Decode(data []byte) (*big.Int, error) {
+	if len(data) != group.elementLength() {
+		return nil, errors.New("FormGroup decodes an element of the wrong length")
+	}
+	x := new(big.Int).SetBytes(data)
+	if _, err := group.Form(x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// elementLength is the byte length of A*2^w + 2A for A < 2^(w-1)
+func (group *FormGroup) elementLength() int {
+	return (int(2*group.w) + 7) / 8
+}
+
+// Binding returns the tag "ClassGroup" followed by |D|
+func (group *FormGroup) Binding() []byte {
+	return append([]byte("ClassGroup"), new(big.Int).Abs(group.D).Bytes()...)
+}
+
+// blindingLength is the bit length of blinding exponents, 256 bits longer than the group order so that g^m is
+// statistically close to uniform in the subgroup generated by g
+func blindingLength(group Group) int {
+	return group.Bound().BitLen() + 256
+}
+
+// multiExp computes g^x * h^r in group
+func multiExp(group Group, g, x, h, r *big.Int) *big.Int {
+	return group.MultiExp([]*big.Int{g, h}, []*big.Int{x, r})
+}
@@ -24,11 +24,7 @@ func ManualBench(counter int) {
 // TimeLockPuzzleManualBenchmark is used for manual benchmark because its running time can be long and varies.
 // This function tests the time it takes to compute g^{2^{counter}} mod N
 func TimeLockPuzzleManualBenchmark(setup *Setup, counter int) {
-	var solution big.Int
-	solution.Set(setup.G)
-	for i := 0; i < counter; i++ {
-		solution.Exp(&solution, big2, setup.N)
-	}
+	NewSquarer(setup.N, setup.G).SquareN(int64(counter))
 }
 
 // ComputeLargeExpManualBenchmark is used for manual benchmark because its running time can be long and varies.
@@ -0,0 +1,892 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// SchemaVersion is the version of the JSON and CBOR schemas of the parameters and proofs in this package
+const SchemaVersion uint = 1
+
+var cborEncMode cbor.EncMode
+
+func init() {
+	var err error
+	cborEncMode, err = cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+}
+
+var errMissingField = errors.New("encoded object misses a field")
+
+// hexInt is a non-negative big.Int encoded as a canonical lowercase hex string with prefix 0x
+type hexInt big.Int
+
+func (x *hexInt) text() string {
+	return "0x" + (*big.Int)(x).Text(16)
+}
+
+func (x *hexInt) setText(s string) error {
+	digits := strings.TrimPrefix(s, "0x")
+	if len(digits) == len(s) || len(digits) == 0 {
+		return fmt.Errorf("integer %q is not a 0x-prefixed hex string", s)
+	}
+	if digits != strings.ToLower(digits) || (len(digits) > 1 && digits[0] == '0') {
+		return fmt.Errorf("integer %q is not canonical", s)
+	}
+	if len(digits) > 2*maxIntBytes {
+		return errIntTooLong
+	}
+	if _, ok := (*big.Int)(x).SetString(digits, 16); !ok {
+		return fmt.Errorf("integer %q is not a hex string", s)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (x *hexInt) MarshalJSON() ([]byte, error) {
+	if (*big.Int)(x).Sign() < 0 {
+		return nil, errNegativeInteger
+	}
+	return json.Marshal(x.text())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (x *hexInt) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return x.setText(s)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (x *hexInt) MarshalCBOR() ([]byte, error) {
+	if (*big.Int)(x).Sign() < 0 {
+		return nil, errNegativeInteger
+	}
+	return cborEncMode.Marshal(x.text())
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (x *hexInt) UnmarshalCBOR(data []byte) error {
+	var s string
+	if err := cbor.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return x.setText(s)
+}
+
+func toHex(x *big.Int) *hexInt {
+	return (*hexInt)(x)
+}
+
+// fromHex copies the decoded integers src into dst, it fails if any integer is missing
+func fromHex(dst []**big.Int, src []*hexInt) error {
+	for i := range src {
+		if src[i] == nil {
+			return errMissingField
+		}
+		*dst[i] = new(big.Int).Set((*big.Int)(src[i]))
+	}
+	return nil
+}
+
+func checkVersion(version uint) error {
+	if version != SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d", version)
+	}
+	return nil
+}
+
+// wireObject is implemented by the wire representations of the types in this package
+type wireObject interface {
+	schemaVersion() uint
+}
+
+type versioned struct {
+	Version uint `json:"version"`
+}
+
+func (v versioned) schemaVersion() uint {
+	return v.Version
+}
+
+func current() versioned {
+	return versioned{Version: SchemaVersion}
+}
+
+func unmarshalJSONWire(data []byte, wire wireObject) error {
+	if err := json.Unmarshal(data, wire); err != nil {
+		return err
+	}
+	return checkVersion(wire.schemaVersion())
+}
+
+func unmarshalCBORWire(data []byte, wire wireObject) error {
+	if err := cbor.Unmarshal(data, wire); err != nil {
+		return err
+	}
+	return checkVersion(wire.schemaVersion())
+}
+
+type groupWire struct {
+	versioned
+	N *hexInt `json:"n"`
+	G *hexInt `json:"g"`
+	H *hexInt `json:"h"`
+}
+
+func (pp *PublicParameters) toWire() *groupWire {
+	return &groupWire{current(), toHex(pp.N), toHex(pp.G), toHex(pp.H)}
+}
+
+func (pp *PublicParameters) fromWire(wire *groupWire) error {
+	return fromHex([]**big.Int{&pp.N, &pp.G, &pp.H}, []*hexInt{wire.N, wire.G, wire.H})
+}
+
+// MarshalJSON implements json.Marshaler
+func (pp *PublicParameters) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pp.toWire())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (pp *PublicParameters) UnmarshalJSON(data []byte) error {
+	var wire groupWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return pp.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (pp *PublicParameters) MarshalCBOR() ([]byte, error) {
+	return cborEncMode.Marshal(pp.toWire())
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (pp *PublicParameters) UnmarshalCBOR(data []byte) error {
+	var wire groupWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return pp.fromWire(&wire)
+}
+
+func (setup *Setup) toWire() *groupWire {
+	return &groupWire{current(), toHex(setup.N), toHex(setup.G), toHex(setup.H)}
+}
+
+func (setup *Setup) fromWire(wire *groupWire) error {
+	return fromHex([]**big.Int{&setup.N, &setup.G, &setup.H}, []*hexInt{wire.N, wire.G, wire.H})
+}
+
+// MarshalJSON implements json.Marshaler
+func (setup *Setup) MarshalJSON() ([]byte, error) {
+	return json.Marshal(setup.toWire())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (setup *Setup) UnmarshalJSON(data []byte) error {
+	var wire groupWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return setup.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (setup *Setup) MarshalCBOR() ([]byte, error) {
+	return cborEncMode.Marshal(setup.toWire())
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (setup *Setup) UnmarshalCBOR(data []byte) error {
+	var wire groupWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return setup.fromWire(&wire)
+}
+
+type rsaPublicWire struct {
+	versioned
+	RSAMod *hexInt `json:"rsaMod"`
+	D      *hexInt `json:"d"`
+}
+
+func (setup *RSAExpPublic) toWire() *rsaPublicWire {
+	return &rsaPublicWire{current(), toHex(setup.RSAMod), toHex(setup.D)}
+}
+
+func (setup *RSAExpPublic) fromWire(wire *rsaPublicWire) error {
+	return fromHex([]**big.Int{&setup.RSAMod, &setup.D}, []*hexInt{wire.RSAMod, wire.D})
+}
+
+// MarshalJSON implements json.Marshaler
+func (setup *RSAExpPublic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(setup.toWire())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (setup *RSAExpPublic) UnmarshalJSON(data []byte) error {
+	var wire rsaPublicWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return setup.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (setup *RSAExpPublic) MarshalCBOR() ([]byte, error) {
+	return cborEncMode.Marshal(setup.toWire())
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (setup *RSAExpPublic) UnmarshalCBOR(data []byte) error {
+	var wire rsaPublicWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return setup.fromWire(&wire)
+}
+
+type pokeStarWire struct {
+	versioned
+	Q *hexInt `json:"q"`
+	R *hexInt `json:"r"`
+}
+
+func (proof *PoKEStarProof) toWire() (*pokeStarWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &pokeStarWire{current(), toHex(proof.Q), toHex(proof.R)}, nil
+}
+
+func (proof *PoKEStarProof) fromWire(wire *pokeStarWire) error {
+	return fromHex([]**big.Int{&proof.Q, &proof.R}, []*hexInt{wire.Q, wire.R})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *PoKEStarProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *PoKEStarProof) UnmarshalJSON(data []byte) error {
+	var wire pokeStarWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *PoKEStarProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *PoKEStarProof) UnmarshalCBOR(data []byte) error {
+	var wire pokeStarWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type zkPoKEWire struct {
+	versioned
+	Z    *hexInt `json:"z"`
+	Ag   *hexInt `json:"ag"`
+	Au   *hexInt `json:"au"`
+	Qg   *hexInt `json:"qg"`
+	Qu   *hexInt `json:"qu"`
+	Rx   *hexInt `json:"rx"`
+	Rrho *hexInt `json:"rrho"`
+}
+
+func (proof *ZKPoKEProof) toWire() (*zkPoKEWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &zkPoKEWire{current(), toHex(proof.z), toHex(proof.Ag), toHex(proof.Au), toHex(proof.Qg),
+		toHex(proof.Qu), toHex(proof.rx), toHex(proof.rrho)}, nil
+}
+
+func (proof *ZKPoKEProof) fromWire(wire *zkPoKEWire) error {
+	return fromHex([]**big.Int{&proof.z, &proof.Ag, &proof.Au, &proof.Qg, &proof.Qu, &proof.rx, &proof.rrho},
+		[]*hexInt{wire.Z, wire.Ag, wire.Au, wire.Qg, wire.Qu, wire.Rx, wire.Rrho})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *ZKPoKEProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *ZKPoKEProof) UnmarshalJSON(data []byte) error {
+	var wire zkPoKEWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *ZKPoKEProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *ZKPoKEProof) UnmarshalCBOR(data []byte) error {
+	var wire zkPoKEWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type poeWire struct {
+	versioned
+	Q *hexInt `json:"q"`
+}
+
+func (proof *PoEProof) toWire() (*poeWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &poeWire{current(), toHex(proof.Q)}, nil
+}
+
+func (proof *PoEProof) fromWire(wire *poeWire) error {
+	return fromHex([]**big.Int{&proof.Q}, []*hexInt{wire.Q})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *PoEProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *PoEProof) UnmarshalJSON(data []byte) error {
+	var wire poeWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *PoEProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *PoEProof) UnmarshalCBOR(data []byte) error {
+	var wire poeWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type pokdeWire struct {
+	versioned
+	Q1 *hexInt `json:"q1"`
+	R1 *hexInt `json:"r1"`
+	Q2 *hexInt `json:"q2"`
+	R2 *hexInt `json:"r2"`
+}
+
+func (proof *PoKDEProof) toWire() (*pokdeWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &pokdeWire{current(), toHex(proof.Q1), toHex(proof.r1), toHex(proof.Q2), toHex(proof.r2)}, nil
+}
+
+func (proof *PoKDEProof) fromWire(wire *pokdeWire) error {
+	return fromHex([]**big.Int{&proof.Q1, &proof.r1, &proof.Q2, &proof.r2}, []*hexInt{wire.Q1, wire.R1, wire.Q2, wire.R2})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *PoKDEProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *PoKDEProof) UnmarshalJSON(data []byte) error {
+	var wire pokdeWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *PoKDEProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *PoKDEProof) UnmarshalCBOR(data []byte) error {
+	var wire pokdeWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type zkPoKDEWire struct {
+	versioned
+	Pi1 *PoKEStarProof `json:"pi1"`
+	D   *hexInt        `json:"d"`
+	E   *hexInt        `json:"e"`
+	F   *hexInt        `json:"f"`
+	K   *hexInt        `json:"k"`
+	Pi2 *PoEProof      `json:"pi2"`
+	Pi3 *ZKPoKEProof   `json:"pi3"`
+	Pi4 *PoKDEProof    `json:"pi4"`
+}
+
+func (proof *ZKPoKDEProof) toWire() (*zkPoKDEWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &zkPoKDEWire{current(), proof.pi1, toHex(proof.D), toHex(proof.E), toHex(proof.F), toHex(proof.K),
+		proof.pi2, proof.pi3, proof.pi4}, nil
+}
+
+func (proof *ZKPoKDEProof) fromWire(wire *zkPoKDEWire) error {
+	if wire.Pi1 == nil || wire.Pi2 == nil || wire.Pi3 == nil || wire.Pi4 == nil {
+		return errMissingField
+	}
+	proof.pi1, proof.pi2, proof.pi3, proof.pi4 = wire.Pi1, wire.Pi2, wire.Pi3, wire.Pi4
+	return fromHex([]**big.Int{&proof.D, &proof.E, &proof.F, &proof.K}, []*hexInt{wire.D, wire.E, wire.F, wire.K})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *ZKPoKDEProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *ZKPoKDEProof) UnmarshalJSON(data []byte) error {
+	var wire zkPoKDEWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *ZKPoKDEProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *ZKPoKDEProof) UnmarshalCBOR(data []byte) error {
+	var wire zkPoKDEWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type zkPoKEModWire struct {
+	versioned
+	D  *hexInt        `json:"d"`
+	Pi *PoKEStarProof `json:"pi"`
+	Q  *hexInt        `json:"q"`
+	R  *hexInt        `json:"r"`
+}
+
+func (proof *ZKPoKEModProof) toWire() (*zkPoKEModWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &zkPoKEModWire{current(), toHex(proof.D), proof.pi, toHex(proof.Q), toHex(proof.r)}, nil
+}
+
+func (proof *ZKPoKEModProof) fromWire(wire *zkPoKEModWire) error {
+	if wire.Pi == nil {
+		return errMissingField
+	}
+	proof.pi = wire.Pi
+	return fromHex([]**big.Int{&proof.D, &proof.Q, &proof.r}, []*hexInt{wire.D, wire.Q, wire.R})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *ZKPoKEModProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *ZKPoKEModProof) UnmarshalJSON(data []byte) error {
+	var wire zkPoKEModWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *ZKPoKEModProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *ZKPoKEModProof) UnmarshalCBOR(data []byte) error {
+	var wire zkPoKEModWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type zkPoMoDEWire struct {
+	versioned
+	D   *hexInt         `json:"d"`
+	C2  *hexInt         `json:"c2"`
+	Pi1 *PoKEStarProof  `json:"pi1"`
+	Pi2 *ZKPoKDEProof   `json:"pi2"`
+	Pi3 *ZKPoKEModProof `json:"pi3"`
+}
+
+func (proof *ZKPoMoDEProof) toWire() (*zkPoMoDEWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &zkPoMoDEWire{current(), toHex(proof.D), toHex(proof.C2), proof.pi1, proof.pi2, proof.pi3}, nil
+}
+
+func (proof *ZKPoMoDEProof) fromWire(wire *zkPoMoDEWire) error {
+	if wire.Pi1 == nil || wire.Pi2 == nil || wire.Pi3 == nil {
+		return errMissingField
+	}
+	proof.pi1, proof.pi2, proof.pi3 = wire.Pi1, wire.Pi2, wire.Pi3
+	return fromHex([]**big.Int{&proof.D, &proof.C2}, []*hexInt{wire.D, wire.C2})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *ZKPoMoDEProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *ZKPoMoDEProof) UnmarshalJSON(data []byte) error {
+	var wire zkPoMoDEWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *ZKPoMoDEProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *ZKPoMoDEProof) UnmarshalCBOR(data []byte) error {
+	var wire zkPoMoDEWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type zkPoMoDEFastWire struct {
+	versioned
+	Pi1 *ZKPoKDEProof   `json:"pi1"`
+	Pi2 *ZKPoKEModProof `json:"pi2"`
+}
+
+func (proof *ZKPoMoDEFastProof) toWire() (*zkPoMoDEFastWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &zkPoMoDEFastWire{current(), proof.pi1, proof.pi2}, nil
+}
+
+func (proof *ZKPoMoDEFastProof) fromWire(wire *zkPoMoDEFastWire) error {
+	if wire.Pi1 == nil || wire.Pi2 == nil {
+		return errMissingField
+	}
+	proof.pi1, proof.pi2 = wire.Pi1, wire.Pi2
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *ZKPoMoDEFastProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *ZKPoMoDEFastProof) UnmarshalJSON(data []byte) error {
+	var wire zkPoMoDEFastWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *ZKPoMoDEFastProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *ZKPoMoDEFastProof) UnmarshalCBOR(data []byte) error {
+	var wire zkPoMoDEFastWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type vtlpVRFWire struct {
+	versioned
+	C2  *hexInt            `json:"c2"`
+	Pi1 *ZKPoMoDEFastProof `json:"pi1"`
+	Pi2 *WesolowskiProof   `json:"pi2"`
+}
+
+func (proof *VTLPVRFProof) toWire() (*vtlpVRFWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &vtlpVRFWire{current(), toHex(proof.C2), proof.pi1, proof.pi2}, nil
+}
+
+func (proof *VTLPVRFProof) fromWire(wire *vtlpVRFWire) error {
+	if wire.Pi1 == nil || wire.Pi2 == nil {
+		return errMissingField
+	}
+	proof.pi1, proof.pi2 = wire.Pi1, wire.Pi2
+	return fromHex([]**big.Int{&proof.C2}, []*hexInt{wire.C2})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *VTLPVRFProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *VTLPVRFProof) UnmarshalJSON(data []byte) error {
+	var wire vtlpVRFWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *VTLPVRFProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *VTLPVRFProof) UnmarshalCBOR(data []byte) error {
+	var wire vtlpVRFWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type wesolowskiWire struct {
+	versioned
+	Pi *hexInt `json:"pi"`
+}
+
+func (proof *WesolowskiProof) toWire() (*wesolowskiWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	return &wesolowskiWire{current(), toHex(proof.Pi)}, nil
+}
+
+func (proof *WesolowskiProof) fromWire(wire *wesolowskiWire) error {
+	return fromHex([]**big.Int{&proof.Pi}, []*hexInt{wire.Pi})
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *WesolowskiProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *WesolowskiProof) UnmarshalJSON(data []byte) error {
+	var wire wesolowskiWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *WesolowskiProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *WesolowskiProof) UnmarshalCBOR(data []byte) error {
+	var wire wesolowskiWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+type pietrzakWire struct {
+	versioned
+	Mu []*hexInt `json:"mu"`
+}
+
+func (proof *PietrzakProof) toWire() (*pietrzakWire, error) {
+	if proof.isEmpty() {
+		return nil, errEmptyProof
+	}
+	wire := &pietrzakWire{current(), make([]*hexInt, len(proof.Mu))}
+	for i := range proof.Mu {
+		wire.Mu[i] = toHex(proof.Mu[i])
+	}
+	return wire, nil
+}
+
+func (proof *PietrzakProof) fromWire(wire *pietrzakWire) error {
+	if wire.Mu == nil {
+		return errMissingField
+	}
+	proof.Mu = make([]*big.Int, len(wire.Mu))
+	dst := make([]**big.Int, len(wire.Mu))
+	for i := range proof.Mu {
+		dst[i] = &proof.Mu[i]
+	}
+	return fromHex(dst, wire.Mu)
+}
+
+// MarshalJSON implements json.Marshaler
+func (proof *PietrzakProof) MarshalJSON() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (proof *PietrzakProof) UnmarshalJSON(data []byte) error {
+	var wire pietrzakWire
+	if err := unmarshalJSONWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
+
+// MarshalCBOR implements cbor.Marshaler
+func (proof *PietrzakProof) MarshalCBOR() ([]byte, error) {
+	wire, err := proof.toWire()
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(wire)
+}
+
+// UnmarshalCBOR implements cbor.Unmarshaler
+func (proof *PietrzakProof) UnmarshalCBOR(data []byte) error {
+	var wire pietrzakWire
+	if err := unmarshalCBORWire(data, &wire); err != nil {
+		return err
+	}
+	return proof.fromWire(&wire)
+}
@@ -0,0 +1,200 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"math/bits"
+)
+
+// montgomery is a fixed-width Montgomery arithmetic modulo an odd m, numbers are little-endian uint64 limbs of the
+// width of m and are kept fully reduced. x is represented by x*R mod m for R = 2^(64*width). The scratch space makes a
+// montgomery unsafe for concurrent use.
+type montgomery struct {
+	modulus *big.Int
+	m       []uint64
+	// k0 = -m^-1 mod 2^64
+	k0 uint64
+	// rr = R^2 mod m converts into the Montgomery representation
+	rr []uint64
+	// t is the scratch space of the double-width products
+	t []uint64
+}
+
+// newMontgomery returns the Montgomery arithmetic modulo m, m has to be odd and larger than 1
+func newMontgomery(m *big.Int) (*montgomery, error) {
+	if m.Sign() <= 0 || m.Bit(0) == 0 || m.Cmp(big1) == 0 {
+		return nil, errors.New("Montgomery arithmetic needs an odd modulus larger than 1")
+	}
+	width := (m.BitLen() + 63) / 64
+	ret := &montgomery{modulus: m, t: make([]uint64, 2*width)}
+	ret.m = ret.limbs(m)
+	// Newton iteration for m^-1 mod 2^64, every step doubles the correct low bits
+	inv := uint64(1)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - ret.m[0]*inv
+	}
+	ret.k0 = -inv
+	rr := new(big.Int).Lsh(big1, uint(128*width))
+	ret.rr = ret.limbs(rr.Mod(rr, m))
+	return ret, nil
+}
+
+// width returns the number of limbs
+func (mont *montgomery) width() int {
+	return len(mont.m)
+}
+
+// limbs returns x in [0, m) as limbs
+func (mont *montgomery) limbs(x *big.Int) []uint64 {
+	width := (x.BitLen() + 63) / 64
+	if mont.m != nil {
+		width = mont.width()
+	}
+	buf := x.FillBytes(make([]byte, 8*width))
+	ret := make([]uint64, width)
+	for i := range ret {
+		ret[i] = binary.BigEndian.Uint64(buf[8*(width-1-i):])
+	}
+	return ret
+}
+
+// int returns the limbs as an integer
+func (mont *montgomery) int(x []uint64) *big.Int {
+	buf := make([]byte, 8*len(x))
+	for i, limb := range x {
+		binary.BigEndian.PutUint64(buf[8*(len(x)-1-i):], limb)
+	}
+	return new(big.Int).SetBytes(buf)
+}
+
+// toMontgomery returns the Montgomery representation of x
+func (mont *montgomery) toMontgomery(x *big.Int) []uint64 {
+	ret := mont.limbs(new(big.Int).Mod(x, mont.modulus))
+	mont.mul(ret, ret, mont.rr)
+	return ret
+}
+
+// fromMontgomery returns the integer represented by x
+func (mont *montgomery) fromMontgomery(x []uint64) *big.Int {
+	t := mont.t
+	for i := range t {
+		t[i] = 0
+	}
+	copy(t, x)
+	ret := make([]uint64, mont.width())
+	mont.redc(ret)
+	return mont.int(ret)
+}
+
+// one returns the Montgomery representation of 1
+func (mont *montgomery) one() []uint64 {
+	return mont.toMontgomery(big1)
+}
+
+// addMulRow sets z += x*y and returns the carry, len(z) has to be len(x). The loop is unrolled by four, which lets the
+// compiler drop the bounds checks and keep the carries in registers.
+func addMulRow(z, x []uint64, y uint64) uint64 {
+	var c uint64
+	z = z[:len(x)]
+	i := 0
+	for ; i+4 <= len(x); i += 4 {
+		x4, z4 := x[i:i+4:i+4], z[i:i+4:i+4]
+		h0, l0 := bits.Mul64(x4[0], y)
+		h1, l1 := bits.Mul64(x4[1], y)
+		h2, l2 := bits.Mul64(x4[2], y)
+		h3, l3 := bits.Mul64(x4[3], y)
+		var cc uint64
+		l0, cc = bits.Add64(l0, c, 0)
+		l1, cc = bits.Add64(l1, h0, cc)
+		l2, cc = bits.Add64(l2, h1, cc)
+		l3, cc = bits.Add64(l3, h2, cc)
+		h3 += cc
+		z4[0], cc = bits.Add64(z4[0], l0, 0)
+		z4[1], cc = bits.Add64(z4[1], l1, cc)
+		z4[2], cc = bits.Add64(z4[2], l2, cc)
+		z4[3], cc = bits.Add64(z4[3], l3, cc)
+		c = h3 + cc
+	}
+	for ; i < len(x); i++ {
+		hi, lo := bits.Mul64(x[i], y)
+		var carry uint64
+		lo, carry = bits.Add64(lo, z[i], 0)
+		hi += carry
+		z[i], carry = bits.Add64(lo, c, 0)
+		c = hi + carry
+	}
+	return c
+}
+
+// mul sets z = x*y/R mod m, z may alias x or y. The product is computed row by row and then reduced.
+func (mont *montgomery) mul(z, x, y []uint64) {
+	width := mont.width()
+	t := mont.t
+	x, y = x[:width], y[:width]
+	for i := range t[:width] {
+		t[i] = 0
+	}
+	// row i adds x*y[i] at limb i, its carry lands on limb i+width which no earlier row has touched
+	for i := 0; i < width; i++ {
+		t[i+width] = addMulRow(t[i:i+width], x, y[i])
+	}
+	mont.redc(z)
+}
+
+// square sets z = x^2/R mod m, z may alias x. The products x[i]*x[j] for i != j are computed once and doubled.
+func (mont *montgomery) square(z, x []uint64) {
+	width := mont.width()
+	t := mont.t
+	x = x[:width]
+	for i := range t[:width] {
+		t[i] = 0
+	}
+	t[2*width-1] = 0
+	// row i adds x[i]*x[j] for j > i at limb i+j
+	for i := 0; i < width-1; i++ {
+		t[i+width] = addMulRow(t[2*i+1:i+width], x[i+1:], x[i])
+	}
+	var carry uint64
+	for i := range t {
+		limb := t[i]
+		t[i] = limb<<1 | carry
+		carry = limb >> 63
+	}
+	carry = 0
+	for i := 0; i < width; i++ {
+		hi, lo := bits.Mul64(x[i], x[i])
+		t[2*i], carry = bits.Add64(t[2*i], lo, carry)
+		t[2*i+1], carry = bits.Add64(t[2*i+1], hi, carry)
+	}
+	mont.redc(z)
+}
+
+// redc sets z = t/R mod m for the double-width t < m*R in the scratch space. The lower half is cancelled limb by limb,
+// the carry out of limb i+width is added one step later.
+func (mont *montgomery) redc(z []uint64) {
+	width := mont.width()
+	t := mont.t
+	var top uint64
+	for i := 0; i < width; i++ {
+		c := addMulRow(t[i:i+width], mont.m, t[i]*mont.k0)
+		t[i+width], top = bits.Add64(t[i+width], c, top)
+	}
+	mont.reduce(z, t[width:], top)
+}
+
+// reduce sets z = t + top*R - m if that is not negative and z = t otherwise, t + top*R is smaller than 2m
+func (mont *montgomery) reduce(z, t []uint64, top uint64) {
+	var borrow uint64
+	for i, limb := range mont.m {
+		_, borrow = bits.Sub64(t[i], limb, borrow)
+	}
+	if top == 0 && borrow != 0 {
+		copy(z, t)
+		return
+	}
+	borrow = 0
+	for i, limb := range mont.m {
+		z[i], borrow = bits.Sub64(t[i], limb, borrow)
+	}
+}
@@ -0,0 +1,330 @@
+package protocol
+
+import (
+	"math/big"
+)
+
+// element is a representation a multi-exponentiation runs on: group elements, Montgomery limbs or reduced forms. nil
+// is the neutral element, so the algorithms never multiply by it.
+type element interface {
+	*big.Int | []uint64 | *Form
+}
+
+// multiplier is the arithmetic a multi-exponentiation runs on, the elements are opaque to the algorithms. mul and
+// square return new elements, the accumulating mulAssign and squareAssign may overwrite x with the result, so x has to
+// be an element owned by the caller.
+type multiplier[T element] interface {
+	mul(x, y T) T
+	square(x T) T
+	mulAssign(x, y T) T
+	squareAssign(x T) T
+	copy(x T) T
+}
+
+// mulOrCopy returns x*y, overwriting x if it is not nil and copying y otherwise, so the result is owned by the caller
+func mulOrCopy[T element](m multiplier[T], x, y T) T {
+	if x == nil {
+		return m.copy(y)
+	}
+	return m.mulAssign(x, y)
+}
+
+// montgomeryQuarterSteps is the cost of a multiplication of montgomery in quarters of a step of big.Int.Exp. The
+// portable rows of montgomery are slower than the assembly of math/big, a multiplication costs about 1.5 steps at 2048
+// bits and 1.9 steps at 3072 bits.
+const montgomeryQuarterSteps = 7
+
+// multiExpWith computes prod bases[i]^exps[i] for non-negative exps with the cheaper of Straus and Pippenger, nil is
+// returned for the neutral element
+func multiExpWith[T element](m multiplier[T], bases []T, exps []*big.Int) T {
+	if c, cost := pippengerWindow(exps); cost < strausCost(exps) {
+		return pippenger(m, bases, exps, c)
+	}
+	return straus(m, bases, exps)
+}
+
+// multiExpCost estimates the multiplications of multiExpWith
+func multiExpCost(exps []*big.Int) int {
+	_, cost := pippengerWindow(exps)
+	if straus := strausCost(exps); straus < cost {
+		return straus
+	}
+	return cost
+}
+
+// separateCost estimates the steps of big.Int.Exp for every exponent, it uses windows of 4 bits and a table of 16
+// powers
+func separateCost(exps []*big.Int) int {
+	cost := 0
+	for _, exp := range exps {
+		cost += exp.BitLen() + exp.BitLen()/4 + 16
+	}
+	return cost
+}
+
+// strausWindow returns the sliding window width w minimising the 2^(w-1) multiplications of the table and the about
+// bits/(w+1) multiplications by table entries
+func strausWindow(bits int) int {
+	best, bestCost := 1, bits
+	for w := 2; w <= 8; w++ {
+		if cost := 1<<(w-1) + bits/(w+1); cost < bestCost {
+			best, bestCost = w, cost
+		}
+	}
+	return best
+}
+
+// strausCost estimates the multiplications of straus, squarings count as multiplications
+func strausCost(exps []*big.Int) int {
+	bits, cost := 0, 0
+	for _, exp := range exps {
+		if exp.BitLen() == 0 {
+			continue
+		}
+		w := strausWindow(exp.BitLen())
+		cost += 1<<(w-1) + exp.BitLen()/(w+1)
+		if exp.BitLen() > bits {
+			bits = exp.BitLen()
+		}
+	}
+	return cost + bits
+}
+
+// pippengerWindow returns the window width c minimising the estimated multiplications of pippenger and the estimate
+func pippengerWindow(exps []*big.Int) (int, int) {
+	bits := 0
+	for _, exp := range exps {
+		if exp.BitLen() > bits {
+			bits = exp.BitLen()
+		}
+	}
+	best, bestCost := 0, -1
+	for c := 1; c <= 16; c++ {
+		cost := bits + (bits+c-1)/c*(len(exps)+2<<c)
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = c, cost
+		}
+	}
+	return best, bestCost
+}
+
+// window is a sliding window of an exponent, the odd digit is multiplied in after the squaring for bit pos
+type window struct {
+	pos   int
+	digit uint
+}
+
+// slidingWindows splits exp into odd digits of at most w bits, from the most significant window down
+func slidingWindows(exp *big.Int, w int) []window {
+	var ret []window
+	for i := exp.BitLen() - 1; i >= 0; {
+		if exp.Bit(i) == 0 {
+			i--
+			continue
+		}
+		j := i - w + 1
+		if j < 0 {
+			j = 0
+		}
+		for exp.Bit(j) == 0 {
+			j++
+		}
+		var digit uint
+		for k := i; k >= j; k-- {
+			digit = digit<<1 | exp.Bit(k)
+		}
+		ret = append(ret, window{pos: j, digit: digit})
+		i = j - 1
+	}
+	return ret
+}
+
+// straus is Shamir's trick with sliding windows: all bases share one chain of squarings, and each base contributes a
+// multiplication by a precomputed odd power per window of its exponent
+func straus[T element](m multiplier[T], bases []T, exps []*big.Int) T {
+	bits := 0
+	tables := make([][]T, len(bases))
+	windows := make([][]window, len(bases))
+	for i, base := range bases {
+		if exps[i].BitLen() == 0 {
+			continue
+		}
+		w := strausWindow(exps[i].BitLen())
+		// tables[i][k] = base^(2k+1)
+		tables[i] = make([]T, 1<<(w-1))
+		tables[i][0] = base
+		if w > 1 {
+			square := m.square(base)
+			for k := 1; k < len(tables[i]); k++ {
+				tables[i][k] = m.mul(tables[i][k-1], square)
+			}
+		}
+		windows[i] = slidingWindows(exps[i], w)
+		if exps[i].BitLen() > bits {
+			bits = exps[i].BitLen()
+		}
+	}
+	var ret T
+	for pos := bits - 1; pos >= 0; pos-- {
+		if ret != nil {
+			ret = m.squareAssign(ret)
+		}
+		for i := range windows {
+			if len(windows[i]) > 0 && windows[i][0].pos == pos {
+				ret = mulOrCopy(m, ret, tables[i][windows[i][0].digit>>1])
+				windows[i] = windows[i][1:]
+			}
+		}
+	}
+	return ret
+}
+
+// pippenger is the bucket method for many bases: per window of c bits every base is multiplied into the bucket of its
+// digit, and the buckets are summed up with running products in 2^(c+1) multiplications
+func pippenger[T element](m multiplier[T], bases []T, exps []*big.Int, c int) T {
+	bits := 0
+	for _, exp := range exps {
+		if exp.BitLen() > bits {
+			bits = exp.BitLen()
+		}
+	}
+	var ret T
+	buckets := make([]T, 1<<c)
+	for start := (bits - 1) / c * c; start >= 0; start -= c {
+		if ret != nil {
+			for k := 0; k < c; k++ {
+				ret = m.squareAssign(ret)
+			}
+		}
+		for k := range buckets {
+			buckets[k] = nil
+		}
+		for i, base := range bases {
+			var digit uint
+			for k := c - 1; k >= 0; k-- {
+				digit = digit<<1 | exps[i].Bit(start+k)
+			}
+			if digit != 0 {
+				buckets[digit] = mulOrCopy(m, buckets[digit], base)
+			}
+		}
+		// sum = prod buckets[k]^k as the product of the running products buckets[top] * ... * buckets[k]
+		var running, sum T
+		for k := len(buckets) - 1; k > 0; k-- {
+			if buckets[k] != nil {
+				running = mulOrCopy(m, running, buckets[k])
+			}
+			if running != nil {
+				sum = mulOrCopy(m, sum, running)
+			}
+		}
+		if sum != nil {
+			ret = mulOrCopy(m, ret, sum)
+		}
+	}
+	return ret
+}
+
+// groupMultiplier runs multi-exponentiations on the Mul of a group
+type groupMultiplier struct {
+	group Group
+}
+
+func (m groupMultiplier) mul(x, y *big.Int) *big.Int {
+	return m.group.Mul(x, y)
+}
+
+func (m groupMultiplier) square(x *big.Int) *big.Int {
+	return m.group.Mul(x, x)
+}
+
+func (m groupMultiplier) mulAssign(x, y *big.Int) *big.Int {
+	return m.mul(x, y)
+}
+
+func (m groupMultiplier) squareAssign(x *big.Int) *big.Int {
+	return m.square(x)
+}
+
+// copy returns x, Mul never modifies its arguments
+func (m groupMultiplier) copy(x *big.Int) *big.Int {
+	return x
+}
+
+// montgomeryMultiplier runs multi-exponentiations on Montgomery representations, the accumulating products work in
+// place and return x unchanged, which spares the allocation of the limbs
+type montgomeryMultiplier struct {
+	mont *montgomery
+}
+
+func (m montgomeryMultiplier) mul(x, y []uint64) []uint64 {
+	ret := make([]uint64, m.mont.width())
+	m.mont.mul(ret, x, y)
+	return ret
+}
+
+func (m montgomeryMultiplier) square(x []uint64) []uint64 {
+	ret := make([]uint64, m.mont.width())
+	m.mont.square(ret, x)
+	return ret
+}
+
+func (m montgomeryMultiplier) mulAssign(x, y []uint64) []uint64 {
+	m.mont.mul(x, x, y)
+	return x
+}
+
+func (m montgomeryMultiplier) squareAssign(x []uint64) []uint64 {
+	m.mont.square(x, x)
+	return x
+}
+
+func (m montgomeryMultiplier) copy(x []uint64) []uint64 {
+	return append([]uint64(nil), x...)
+}
+
+// formMultiplier runs multi-exponentiations on reduced forms
+type formMultiplier struct {
+	group *ClassGroup
+}
+
+func (m formMultiplier) mul(x, y *Form) *Form {
+	return m.group.compose(x, y)
+}
+
+func (m formMultiplier) square(x *Form) *Form {
+	return m.group.square(x)
+}
+
+func (m formMultiplier) mulAssign(x, y *Form) *Form {
+	return m.mul(x, y)
+}
+
+func (m formMultiplier) squareAssign(x *Form) *Form {
+	return m.square(x)
+}
+
+// copy returns x, compose and square never modify their arguments
+func (m formMultiplier) copy(x *Form) *Form {
+	return x
+}
+
+// multiExpSeparately computes prod bases[i]^exps[i] with one exponentiation per base, for exponents the
+// multi-exponentiations cannot handle
+func multiExpSeparately(group Group, bases, exps []*big.Int) *big.Int {
+	ret := group.Identity()
+	for i, base := range bases {
+		ret = group.Mul(ret, group.Exp(base, exps[i]))
+	}
+	return ret
+}
+
+// hasNegative returns true if one of exps is negative
+func hasNegative(exps []*big.Int) bool {
+	for _, exp := range exps {
+		if exp.Sign() < 0 {
+			return true
+		}
+	}
+	return false
+}
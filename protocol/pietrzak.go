@@ -0,0 +1,161 @@
+package protocol
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+// PietrzakProof contains the proof for the solution y = Z^{2^T} of a puzzle, Mu[i] is the midpoint x^{2^{T/2}} of
+// round i of the halving protocol. It has about log2(T) group elements and needs no hashing to primes.
+type PietrzakProof struct {
+	Mu []*big.Int
+}
+
+func (proof *PietrzakProof) isEmpty() bool {
+	if proof.Mu == nil {
+		return true
+	}
+	for i := range proof.Mu {
+		if proof.Mu[i] == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// pietrzakRounds returns the number of halving rounds for a puzzle with hardness T
+func pietrzakRounds(T int64) int {
+	rounds := 0
+	for ; T > 1; rounds++ {
+		T = (T + 1) / 2
+	}
+	return rounds
+}
+
+// pietrzakTerm is the factor P(t)^c of an element prod_s P(t_s)^{c_s}, where P(t) = Z^{2^t}
+type pietrzakTerm struct {
+	c *big.Int
+	t int64
+}
+
+// pietrzakPoint returns P(t) = Z^{2^t} from the closest intermediate value, points[i] = P(ik) for ik < T and y = P(T)
+func pietrzakPoint(group Group, puzzle *Puzzle, y *big.Int, points []*big.Int, k, t int64) *big.Int {
+	ret, steps := y, t-puzzle.T
+	if t < puzzle.T {
+		ret, steps = points[t/k], t%k
+	}
+	for ; steps > 0; steps-- {
+		ret = group.Mul(ret, ret)
+	}
+	return ret
+}
+
+// PietrzakProve proves y = Z^{2^T} given points[i] = Z^{2^{ik}} for every ik < T, as kept by SolvePuzzleWithProof.
+// Each round halves T: with midpoint mu = x^{2^{T/2}} and challenge r, the statement becomes (x^r*mu)^{2^{T/2}} = mu^r*y.
+// An odd T is padded by replacing y with y^2. The first midpoints are computed from the intermediate values, once this
+// costs more than T/2 squarings the prover squares the current x directly.
+func PietrzakProve(puzzle *Puzzle, y *big.Int, points []*big.Int, k int64) (*PietrzakProof, error) {
+	if k <= 0 || int64(len(points)) != (puzzle.T+k-1)/k {
+		return nil, errors.New("PietrzakProve inputs a wrong number of intermediate values")
+	}
+	var ret PietrzakProof
+	ret.Mu = make([]*big.Int, 0, pietrzakRounds(puzzle.T))
+	transcript := puzzleTranscript("Pietrzak", puzzle, y)
+
+	group := puzzle.group()
+	x, yi := puzzle.Z, y
+	terms := []pietrzakTerm{{big.NewInt(1), 0}}
+	for T := puzzle.T; T > 1; {
+		if T%2 == 1 {
+			yi = group.Mul(yi, yi)
+			T++
+		}
+		half := T / 2
+		var mu *big.Int
+		if terms != nil && int64(len(terms))*(int64(terms[len(terms)-1].c.BitLen())+k) >= half {
+			// the intermediate values no longer help, square x directly from now on
+			terms = nil
+		}
+		if terms != nil {
+			mu = group.Identity()
+			for _, term := range terms {
+				mu = group.Mul(mu, group.Exp(pietrzakPoint(group, puzzle, y, points, k, term.t+half), term.c))
+			}
+		} else {
+			mu = x
+			for i := int64(0); i < half; i++ {
+				mu = group.Mul(mu, mu)
+			}
+		}
+		ret.Mu = append(ret.Mu, mu)
+		transcript.AppendInts("mu", mu)
+		r := transcript.IntChallenge("r")
+
+		x = group.MultiExp([]*big.Int{x, mu}, []*big.Int{r, big1})
+		yi = group.MultiExp([]*big.Int{mu, yi}, []*big.Int{r, big1})
+		if terms != nil {
+			next := make([]pietrzakTerm, 0, 2*len(terms))
+			for _, term := range terms {
+				next = append(next, pietrzakTerm{new(big.Int).Mul(term.c, r), term.t})
+			}
+			for _, term := range terms {
+				next = append(next, pietrzakTerm{term.c, term.t + half})
+			}
+			terms = next
+		}
+		T = half
+	}
+	return &ret, nil
+}
+
+// Validate checks the puzzle, that y and every Mu are elements of the group of the puzzle and that there is one Mu per halving round.
+// RSA puzzles run in QR_N^+, so y and Mu must be canonical |x|: in Z_N^* a prover could flip the sign of y or of a midpoint,
+// which only changes the checked equation by the element -1 of order 2.
+func (proof *PietrzakProof) Validate(puzzle *Puzzle, y *big.Int) error {
+	v := newValidator("Pietrzak", nil)
+	v.puzzle(puzzle)
+	v.element("y", y)
+	if v.present("proof", proof != nil) {
+		if rounds := pietrzakRounds(puzzle.T); len(proof.Mu) != rounds {
+			v.fail("Mu", "has "+strconv.Itoa(len(proof.Mu))+" elements instead of "+strconv.Itoa(rounds))
+		}
+		for i, mu := range proof.Mu {
+			v.element("Mu["+strconv.Itoa(i)+"]", mu)
+		}
+	}
+	return v.result()
+}
+
+// PietrzakVerify checks the proof that y = Z^{2^T}, returns true if everything is good
+func PietrzakVerify(puzzle *Puzzle, y *big.Int, proof *PietrzakProof) bool {
+	return PietrzakVerifyErr(puzzle, y, proof) == nil
+}
+
+// PietrzakVerifyErr checks the proof that y = Z^{2^T}, returns nil if everything is good and otherwise the
+// reason of the failure
+func PietrzakVerifyErr(puzzle *Puzzle, y *big.Int, proof *PietrzakProof) error {
+	if err := proof.Validate(puzzle, y); err != nil {
+		return err
+	}
+	transcript := puzzleTranscript("Pietrzak", puzzle, y)
+
+	group := puzzle.group()
+	x, yi := puzzle.Z, y
+	T := puzzle.T
+	for _, mu := range proof.Mu {
+		if T%2 == 1 {
+			yi = group.Mul(yi, yi)
+			T++
+		}
+		transcript.AppendInts("mu", mu)
+		r := transcript.IntChallenge("r")
+		x = group.MultiExp([]*big.Int{x, mu}, []*big.Int{r, big1})
+		yi = group.MultiExp([]*big.Int{mu, yi}, []*big.Int{r, big1})
+		T /= 2
+	}
+	if !group.Equal(group.Mul(x, x), yi) {
+		return challengeMismatch("Pietrzak", "x^2 = y in the last round")
+	}
+	return nil
+}
@@ -22,17 +22,48 @@ func (proof *PoKDEProof) isEmpty() bool {
 	return false
 }
 
+// Validate checks that C1, C2, Q1 and Q2 are group elements, that e is positive and that r1 and r2 can be remainders
+// modulo a challenge
+func (proof *PoKDEProof) Validate(pp *PublicParameters, C1, C2, e *big.Int) error {
+	v := newValidator("PoKDE", nil)
+	v.parameters(pp)
+	v.element("C1", C1)
+	v.element("C2", C2)
+	v.positive("e", e)
+	if v.present("proof", proof != nil) {
+		v.element("Q1", proof.Q1)
+		v.element("Q2", proof.Q2)
+		v.remainder("r1", proof.r1, maxChallengeBits)
+		v.remainder("r2", proof.r2, maxChallengeBits)
+	}
+	return v.result()
+}
+
+// pokdeChallenge returns the prime challenge of a PoKDE proof of C1 = g^x, C2 = g^{x^e} continuing parent
+func pokdeChallenge(parent *fiatshamir.Transcript, pp *PublicParameters, C1, C2, e *big.Int) *big.Int {
+	transcript := newTranscript(parent, "PoKDE", pp.group())
+	transcript.AppendInts("G", pp.G)
+	transcript.AppendInts("C1", C1)
+	transcript.AppendInts("C2", C2)
+	transcript.AppendInts("e", e)
+	return transcript.PrimeChallenge("l")
+}
+
 // PoKDEProve prove C1=g^x, C2=g^{x^e}
 func PoKDEProve(pp *PublicParameters, C1, C2, x, e *big.Int) (*PoKDEProof, error) {
+	return pokdeProve(nil, pp, C1, C2, x, e)
+}
+
+// pokdeProve is PoKDEProve continuing parent
+func pokdeProve(parent *fiatshamir.Transcript, pp *PublicParameters, C1, C2, x, e *big.Int) (*PoKDEProof, error) {
 	var xe, l, q1, q2, r1, r2 big.Int
 	xe.Exp(x, e, nil)
-	transcript := fiatshamir.InitTranscript([]string{"PoKDE", pp.G.String(), pp.N.String(), C1.String(), C1.String(), e.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	l.Set(pokdeChallenge(parent, pp, C1, C2, e))
 	var ret PoKDEProof
 	q1.DivMod(x, &l, &r1)
 	q2.DivMod(&xe, &l, &r2)
-	ret.Q1 = new(big.Int).Exp(pp.G, &q1, pp.N)
-	ret.Q2 = new(big.Int).Exp(pp.G, &q2, pp.N)
+	ret.Q1 = pp.expG(&q1)
+	ret.Q2 = pp.expG(&q2)
 	ret.r1 = new(big.Int).Set(&r1)
 	ret.r2 = new(big.Int).Set(&r2)
 
@@ -41,21 +72,35 @@ func PoKDEProve(pp *PublicParameters, C1, C2, x, e *big.Int) (*PoKDEProof, error
 
 // PoKDEVerify checks the proof, returns true if everything is good
 func PoKDEVerify(pp *PublicParameters, C1, C2, e *big.Int, proof *PoKDEProof) bool {
-	if proof.isEmpty() {
-		return false
+	return PoKDEVerifyErr(pp, C1, C2, e, proof) == nil
+}
+
+// PoKDEVerifyErr checks the proof, returns nil if everything is good and otherwise the reason of the failure
+func PoKDEVerifyErr(pp *PublicParameters, C1, C2, e *big.Int, proof *PoKDEProof) error {
+	return pokdeVerify(nil, pp, C1, C2, e, proof)
+}
+
+// pokdeVerify is PoKDEVerifyErr continuing parent
+func pokdeVerify(parent *fiatshamir.Transcript, pp *PublicParameters, C1, C2, e *big.Int, proof *PoKDEProof) error {
+	if err := proof.Validate(pp, C1, C2, e); err != nil {
+		return err
+	}
+	group := pp.group()
+	var l big.Int
+	l.Set(pokdeChallenge(parent, pp, C1, C2, e))
+	if proof.r1.Cmp(&l) != -1 {
+		return malformed("PoKDE", "r1", "is not smaller than the challenge")
+	}
+	if proof.r2.Cmp(&l) != -1 {
+		return malformed("PoKDE", "r2", "is not smaller than the challenge")
 	}
-	var l, temp big.Int
-	transcript := fiatshamir.InitTranscript([]string{"PoKDE", pp.G.String(), pp.N.String(), C1.String(), C1.String(), e.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
-	if proof.r1.Cmp(&l) != -1 || proof.r2.Cmp(&l) != -1 {
-		return false
+	if !group.Equal(multiExp(group, proof.Q1, &l, pp.G, proof.r1), C1) {
+		return challengeMismatch("PoKDE", "Q1^l * g^r1 = C1")
 	}
-	temp.Set(MultiExp(proof.Q1, &l, pp.G, proof.r1, pp.N))
-	if temp.Cmp(C1) != 0 {
-		return false
+	if !group.Equal(multiExp(group, proof.Q2, &l, pp.G, proof.r2), C2) {
+		return challengeMismatch("PoKDE", "Q2^l * g^r2 = C2")
 	}
-	temp.Set(MultiExp(proof.Q2, &l, pp.G, proof.r2, pp.N))
-	return temp.Cmp(C2) == 0
+	return nil
 }
 
 // ZKPoKDEProof contains the proofs for PoKDE
@@ -79,11 +124,50 @@ func (proof *ZKPoKDEProof) isEmpty() bool {
 	return false
 }
 
+// Validate checks that C1, C2 and the proof elements are group elements, that e is positive and that every sub-proof
+// is present, the sub-proofs are validated by their verifiers
+func (proof *ZKPoKDEProof) Validate(pp *PublicParameters, C1, C2, e *big.Int) error {
+	v := newValidator("ZKPoKDE", nil)
+	v.parameters(pp)
+	v.element("C1", C1)
+	v.element("C2", C2)
+	v.positive("e", e)
+	if v.present("proof", proof != nil) {
+		v.element("D", proof.D)
+		v.element("E", proof.E)
+		v.element("F", proof.F)
+		v.element("K", proof.K)
+		v.present("pi1", proof.pi1 != nil)
+		v.present("pi2", proof.pi2 != nil)
+		v.present("pi3", proof.pi3 != nil)
+		v.present("pi4", proof.pi4 != nil)
+	}
+	return v.result()
+}
+
+// zkpokdeTranscript returns the transcript of a ZKPoKDE proof continuing parent after its statement, the sub-proofs
+// are forked from it
+func zkpokdeTranscript(parent *fiatshamir.Transcript, pp *PublicParameters, C1, C2, e *big.Int) *fiatshamir.Transcript {
+	transcript := newTranscript(parent, "ZKPoKDE", pp.group())
+	transcript.AppendInts("G", pp.G)
+	transcript.AppendInts("H", pp.H)
+	transcript.AppendInts("C1", C1)
+	transcript.AppendInts("C2", C2)
+	transcript.AppendInts("e", e)
+	return transcript
+}
+
 // ZKPoKDEProve prove C1=g^x, C2=g^{x^e} in zero-knowledge
 func ZKPoKDEProve(pp *PublicParameters, C1, C2, x, e *big.Int) (*ZKPoKDEProof, error) {
+	return zkpokdeProve(nil, pp, C1, C2, x, e)
+}
+
+// zkpokdeProve is ZKPoKDEProve continuing parent
+func zkpokdeProve(parent *fiatshamir.Transcript, pp *PublicParameters, C1, C2, x, e *big.Int) (*ZKPoKDEProof, error) {
+	group := pp.group()
 	var ret ZKPoKDEProof
 
-	length := 2048 + 256
+	length := blindingLength(group)
 	var b big.Int
 	b.SetInt64(1)
 	b.Lsh(&b, uint(length))
@@ -91,28 +175,29 @@ func ZKPoKDEProve(pp *PublicParameters, C1, C2, x, e *big.Int) (*ZKPoKDEProof, e
 	if err != nil {
 		return nil, err
 	}
-	ret.D = new(big.Int).Exp(pp.G, m, pp.N)
-	proof, err := PoKEStarProve(pp, ret.D, m)
+	ret.D = pp.expG(m)
+	transcript := zkpokdeTranscript(parent, pp, C1, C2, e)
+	proof, err := pokeStarProve(transcript.Fork("pi1"), pp, ret.D, m)
 	if err != nil {
 		return nil, err
 	}
 	ret.pi1 = proof
 
 	var l, xl, gamma, z, z2e, omega, omegaPrime, temp big.Int
-	transcript := fiatshamir.InitTranscript([]string{"ZKPoKDE", pp.G.String(), pp.H.String(),
-		pp.N.String(), C1.String(), C1.String(), e.String(), ret.pi1.Q.String(), ret.pi1.R.String(), ret.D.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
-	gamma.Set(transcript.GetLargeChallengeUsingTranscript(length))
+	transcript.AppendInts("D", ret.D)
+	transcript.AppendInts("pi1", ret.pi1.Q, ret.pi1.R)
+	l.Set(transcript.PrimeChallenge("l"))
+	gamma.Set(transcript.LargeChallenge("gamma", length))
 
 	// z = x*l + m + gamma E = g^{z^e}
 	xl.Mul(x, &l)
 	z.Add(&xl, m)
 	z.Add(&z, &gamma)
 	z2e.Exp(&z, e, nil)
-	ret.E = new(big.Int).Exp(pp.G, &z2e, pp.N)
+	ret.E = pp.expG(&z2e)
 	temp.Exp(&l, e, nil)
-	ret.K = new(big.Int).Exp(C2, &temp, pp.N)
-	temp1Proof, err := PoEProve(C2, pp.N, ret.K, new(big.Int).Set(&temp))
+	ret.K = group.Exp(C2, &temp)
+	temp1Proof, err := poeProve(transcript.Fork("pi2"), group, C2, ret.K, new(big.Int).Set(&temp))
 	if err != nil {
 		return nil, err
 	}
@@ -120,17 +205,17 @@ func ZKPoKDEProve(pp *PublicParameters, C1, C2, x, e *big.Int) (*ZKPoKDEProof, e
 	// omega = z^e - (xl)^e
 	omega.Exp(&xl, e, nil)
 	omega.Sub(&z2e, &omega)
-	ret.F = new(big.Int).Exp(pp.G, &omega, pp.N)
+	ret.F = pp.expG(&omega)
 	temp.Add(m, &gamma)
 	omegaPrime.Div(&omega, &temp)
-	temp2Proof, err := ZKPoKEProve(pp, new(big.Int).Exp(pp.G, &temp, pp.N), &omegaPrime, ret.F)
+	temp2Proof, err := zkpokeProve(transcript.Fork("pi3"), pp, pp.expG(&temp), &omegaPrime, ret.F)
 	if err != nil {
 		return nil, err
 	}
 	ret.pi3 = temp2Proof
 
 	// temp = C1^l * D * g^gamma = g^z
-	temp3Proof, err := PoKDEProve(pp, new(big.Int).Exp(pp.G, &z, pp.N), ret.F, &z, e)
+	temp3Proof, err := pokdeProve(transcript.Fork("pi4"), pp, pp.expG(&z), ret.E, &z, e)
 	if err != nil {
 		return nil, err
 	}
@@ -141,38 +226,46 @@ func ZKPoKDEProve(pp *PublicParameters, C1, C2, x, e *big.Int) (*ZKPoKDEProof, e
 
 // ZKPoKDEVerify checks C1=g^x, C2=g^{x^e}, returns true is everything is correct
 func ZKPoKDEVerify(pp *PublicParameters, C1, C2, e *big.Int, proof *ZKPoKDEProof) bool {
-	if pp == nil || proof.isEmpty() {
-		return false
+	return ZKPoKDEVerifyErr(pp, C1, C2, e, proof) == nil
+}
+
+// ZKPoKDEVerifyErr checks C1=g^x, C2=g^{x^e}, returns nil if everything is correct and otherwise the reason of the
+// failure, a failed sub-proof is reported as a *SubproofError
+func ZKPoKDEVerifyErr(pp *PublicParameters, C1, C2, e *big.Int, proof *ZKPoKDEProof) error {
+	return zkpokdeVerify(nil, pp, C1, C2, e, proof)
+}
+
+// zkpokdeVerify is ZKPoKDEVerifyErr continuing parent
+func zkpokdeVerify(parent *fiatshamir.Transcript, pp *PublicParameters, C1, C2, e *big.Int, proof *ZKPoKDEProof) error {
+	if err := proof.Validate(pp, C1, C2, e); err != nil {
+		return err
 	}
 
-	if !PoKEStarVerify(pp, proof.D, proof.pi1) {
-		return false
+	transcript := zkpokdeTranscript(parent, pp, C1, C2, e)
+	if err := pokeStarVerify(transcript.Fork("pi1"), pp, proof.D, proof.pi1); err != nil {
+		return subproofFailed("ZKPoKDE", "pi1", err)
 	}
-	var l, gamma, temp big.Int
-	length := 2048 + 256
-	transcript := fiatshamir.InitTranscript([]string{"ZKPoKDE", pp.G.String(), pp.H.String(),
-		pp.N.String(), C1.String(), C1.String(), e.String(), proof.pi1.Q.String(), proof.pi1.R.String(), proof.D.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
-	gamma.Set(transcript.GetLargeChallengeUsingTranscript(length))
-	temp.Mul(proof.F, proof.K)
-	temp.Mod(&temp, pp.N)
-	if temp.Cmp(proof.E) != 0 {
-		return false
+	group := pp.group()
+	var l, gamma, le big.Int
+	length := blindingLength(group)
+	transcript.AppendInts("D", proof.D)
+	transcript.AppendInts("pi1", proof.pi1.Q, proof.pi1.R)
+	l.Set(transcript.PrimeChallenge("l"))
+	gamma.Set(transcript.LargeChallenge("gamma", length))
+	if !group.Equal(group.Mul(proof.F, proof.K), proof.E) {
+		return challengeMismatch("ZKPoKDE", "F * K = E")
 	}
 
-	temp.Exp(&l, e, nil)
-	if !PoEVerify(C2, pp.N, proof.K, &temp, proof.pi2) {
-		return false
+	le.Exp(&l, e, nil)
+	if err := poeVerify(transcript.Fork("pi2"), group, C2, proof.K, &le, proof.pi2); err != nil {
+		return subproofFailed("ZKPoKDE", "pi2", err)
 	}
 	//temp = D * g^gamma
-	temp.Exp(pp.G, &gamma, pp.N)
-	temp.Mul(&temp, proof.D)
-	temp.Mod(&temp, pp.N)
-	if !ZKPoKEVerify(pp, &temp, proof.F, proof.pi3) {
-		return false
+	temp := group.Mul(pp.expG(&gamma), proof.D)
+	if err := zkpokeVerify(transcript.Fork("pi3"), pp, temp, proof.F, proof.pi3); err != nil {
+		return subproofFailed("ZKPoKDE", "pi3", err)
 	}
 	//temp = C1^l * D * g^gamma
-	temp.Mul(&temp, new(big.Int).Exp(C1, &l, pp.N))
-	temp.Mod(&temp, pp.N)
-	return PoKDEVerify(pp, &temp, proof.E, e, proof.pi4)
+	temp = group.Mul(temp, group.Exp(C1, &l))
+	return subproofFailed("ZKPoKDE", "pi4", pokdeVerify(transcript.Fork("pi4"), pp, temp, proof.E, e, proof.pi4))
 }
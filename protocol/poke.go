@@ -14,12 +14,7 @@ import (
 
 // MultiExp computes g^x * h^r mod n
 func MultiExp(g, x, h, r, n *big.Int) *big.Int {
-	var temp1, temp2 big.Int
-	temp1.Exp(g, x, n)
-	temp2.Exp(h, r, n)
-	temp1.Mul(&temp1, &temp2)
-	temp1.Mod(&temp1, n)
-	return &temp1
+	return multiExp(NewRSAGroup(n), g, x, h, r)
 }
 
 // PoKEStarProof contains the proofs for PoKE
@@ -35,38 +30,76 @@ func (proof *PoKEStarProof) isEmpty() bool {
 	return false
 }
 
+// Validate checks that C and the proof elements are group elements and that R can be a remainder modulo a challenge
+func (proof *PoKEStarProof) Validate(pp *PublicParameters, C *big.Int) error {
+	v := newValidator("PoKEStar", nil)
+	v.parameters(pp)
+	v.element("C", C)
+	if v.present("proof", proof != nil) {
+		v.element("Q", proof.Q)
+		v.remainder("R", proof.R, maxChallengeBits)
+	}
+	return v.result()
+}
+
+// pokeStarChallenge returns the prime challenge of a PoKEStar proof of C continuing parent
+func pokeStarChallenge(parent *fiatshamir.Transcript, pp *PublicParameters, C *big.Int) *big.Int {
+	transcript := newTranscript(parent, "PoKEStar", pp.group())
+	transcript.AppendInts("G", pp.G)
+	transcript.AppendInts("C", C)
+	return transcript.PrimeChallenge("l")
+}
+
 // PoKEStarProve proves knowledge of x s.t.  g^x = C
 func PoKEStarProve(pp *PublicParameters, C, x *big.Int) (*PoKEStarProof, error) {
+	return pokeStarProve(nil, pp, C, x)
+}
+
+// pokeStarProve is PoKEStarProve continuing parent
+func pokeStarProve(parent *fiatshamir.Transcript, pp *PublicParameters, C, x *big.Int) (*PoKEStarProof, error) {
 	if x == nil || pp == nil {
 		return nil, errors.New("PoKEStarProof input is nil")
 	}
+	group := pp.group()
 	var ret PoKEStarProof
-	ret.Q = new(big.Int)
 	ret.R = new(big.Int)
-	var temp, q, l big.Int
-	temp.Exp(pp.G, x, pp.N)
-	if temp.Cmp(C) != 0 {
+	var q, l big.Int
+	if !group.Equal(pp.expG(x), C) {
 		return nil, errors.New("PoKEStar inputs a invalid statement")
 	}
 
-	transcript := fiatshamir.InitTranscript([]string{"PoKEStar", pp.G.String(), pp.N.String(), C.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	l.Set(pokeStarChallenge(parent, pp, C))
 	q.DivMod(x, &l, ret.R)
-	ret.Q.Exp(pp.G, &q, pp.N)
+	ret.Q = pp.expG(&q)
 	return &ret, nil
 }
 
 // PoKEStarVerify checks the proof, returns true if everything is good
 func PoKEStarVerify(pp *PublicParameters, C *big.Int, proof *PoKEStarProof) bool {
-	if proof == nil || proof.isEmpty() {
-		return false
+	return PoKEStarVerifyErr(pp, C, proof) == nil
+}
+
+// PoKEStarVerifyErr checks the proof, returns nil if everything is good and otherwise the reason of the failure
+func PoKEStarVerifyErr(pp *PublicParameters, C *big.Int, proof *PoKEStarProof) error {
+	return pokeStarVerify(nil, pp, C, proof)
+}
+
+// pokeStarVerify is PoKEStarVerifyErr continuing parent
+func pokeStarVerify(parent *fiatshamir.Transcript, pp *PublicParameters, C *big.Int, proof *PoKEStarProof) error {
+	if err := proof.Validate(pp, C); err != nil {
+		return err
+	}
+	group := pp.group()
+	var l big.Int
+	l.Set(pokeStarChallenge(parent, pp, C))
+	if proof.R.Cmp(&l) >= 0 {
+		return malformed("PoKEStar", "R", "is not smaller than the challenge")
 	}
-	var temp, l big.Int
-	transcript := fiatshamir.InitTranscript([]string{"PoKEStar", pp.G.String(), pp.N.String(), C.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
 
-	temp.Set(MultiExp(proof.Q, &l, pp.G, proof.R, pp.N))
-	return temp.Cmp(C) == 0
+	if !group.Equal(multiExp(group, proof.Q, &l, pp.G, proof.R), C) {
+		return challengeMismatch("PoKEStar", "Q^l * g^R = C")
+	}
+	return nil
 }
 
 // ZKPoKEProof contains the proofs for ZKPoKE
@@ -88,16 +121,54 @@ func (proof *ZKPoKEProof) isEmpty() bool {
 	return false
 }
 
+// Validate checks that u, w and the proof elements are group elements and that rx and rrho can be remainders modulo a
+// challenge
+func (proof *ZKPoKEProof) Validate(pp *PublicParameters, u, w *big.Int) error {
+	v := newValidator("ZKPoKE", nil)
+	v.parameters(pp)
+	v.element("u", u)
+	v.element("w", w)
+	if v.present("proof", proof != nil) {
+		v.element("z", proof.z)
+		v.element("Ag", proof.Ag)
+		v.element("Au", proof.Au)
+		v.element("Qg", proof.Qg)
+		v.element("Qu", proof.Qu)
+		v.remainder("rx", proof.rx, maxChallengeBits)
+		v.remainder("rrho", proof.rrho, maxChallengeBits)
+	}
+	return v.result()
+}
+
+// zkpokeTranscript returns the transcript of a ZKPoKE proof continuing parent after its commitments z, Ag and Au
+func zkpokeTranscript(parent *fiatshamir.Transcript, pp *PublicParameters, u, w *big.Int,
+	proof *ZKPoKEProof) *fiatshamir.Transcript {
+	transcript := newTranscript(parent, "ZKPoKE", pp.group())
+	transcript.AppendInts("G", pp.G)
+	transcript.AppendInts("H", pp.H)
+	transcript.AppendInts("u", u)
+	transcript.AppendInts("w", w)
+	transcript.AppendInts("z", proof.z)
+	transcript.AppendInts("Ag", proof.Ag)
+	transcript.AppendInts("Au", proof.Au)
+	return transcript
+}
+
 // ZKPoKEProve proves in zero-knowledge of knowledge x s.t. u^x =w mod N
 func ZKPoKEProve(pp *PublicParameters, u, x, w *big.Int) (*ZKPoKEProof, error) {
+	return zkpokeProve(nil, pp, u, x, w)
+}
+
+// zkpokeProve is ZKPoKEProve continuing parent
+func zkpokeProve(parent *fiatshamir.Transcript, pp *PublicParameters, u, x, w *big.Int) (*ZKPoKEProof, error) {
+	group := pp.group()
 	var ret ZKPoKEProof
-	var temp, c, l big.Int
-	temp.Exp(u, x, pp.N)
-	if temp.Cmp(w) != 0 {
+	var c, l big.Int
+	if !group.Equal(group.Exp(u, x), w) {
 		return nil, errors.New("ZKPoKEProve inputs a invalid statement")
 	}
 
-	b := new(big.Int).Set(pp.N)
+	b := new(big.Int).Set(group.Bound())
 	lsh := 2*securityPara - 2
 	b.Lsh(b, uint(lsh))
 	k, err := rand.Int(rand.Reader, b)
@@ -113,14 +184,13 @@ func ZKPoKEProve(pp *PublicParameters, u, x, w *big.Int) (*ZKPoKEProof, error) {
 		return nil, err
 	}
 
-	ret.z = new(big.Int).Set(MultiExp(pp.G, x, pp.H, rhox, pp.N))
-	ret.Ag = new(big.Int).Set(MultiExp(pp.G, k, pp.H, rhok, pp.N))
-	ret.Au = new(big.Int).Exp(u, k, pp.N)
+	ret.z = pp.multiExpGH(x, rhox)
+	ret.Ag = pp.multiExpGH(k, rhok)
+	ret.Au = group.Exp(u, k)
 
-	transcript := fiatshamir.InitTranscript([]string{"ZKPoKE", pp.G.String(), pp.H.String(),
-		pp.N.String(), u.String(), w.String(), ret.z.String(), ret.Ag.String(), ret.Au.String()}, fiatshamir.Max252)
-	c.Set(transcript.GetIntChallengeUsingTranscript())
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	transcript := zkpokeTranscript(parent, pp, u, w, &ret)
+	c.Set(transcript.IntChallenge("c"))
+	l.Set(transcript.PrimeChallenge("l"))
 
 	var sx, srho big.Int //sx = k+ cx, srho = rhok + c*rhox
 	sx.Mul(&c, x)
@@ -132,8 +202,8 @@ func ZKPoKEProve(pp *PublicParameters, u, x, w *big.Int) (*ZKPoKEProof, error) {
 	qx.DivMod(&sx, &l, &rx)
 	qrho.DivMod(&srho, &l, &rrho)
 
-	ret.Qg = new(big.Int).Set(MultiExp(pp.G, &qx, pp.H, &qrho, pp.N))
-	ret.Qu = new(big.Int).Exp(u, &qx, pp.N)
+	ret.Qg = pp.multiExpGH(&qx, &qrho)
+	ret.Qu = group.Exp(u, &qx)
 	ret.rx = new(big.Int).Set(&rx)
 	ret.rrho = new(big.Int).Set(&rrho)
 
@@ -142,32 +212,43 @@ func ZKPoKEProve(pp *PublicParameters, u, x, w *big.Int) (*ZKPoKEProof, error) {
 
 // ZKPoKEVerify checks the proof, returns true if everything is good
 func ZKPoKEVerify(pp *PublicParameters, u, w *big.Int, proof *ZKPoKEProof) bool {
-	if proof == nil || proof.isEmpty() {
-		return false
+	return ZKPoKEVerifyErr(pp, u, w, proof) == nil
+}
+
+// ZKPoKEVerifyErr checks the proof, returns nil if everything is good and otherwise the reason of the failure
+func ZKPoKEVerifyErr(pp *PublicParameters, u, w *big.Int, proof *ZKPoKEProof) error {
+	return zkpokeVerify(nil, pp, u, w, proof)
+}
+
+// zkpokeVerify is ZKPoKEVerifyErr continuing parent
+func zkpokeVerify(parent *fiatshamir.Transcript, pp *PublicParameters, u, w *big.Int, proof *ZKPoKEProof) error {
+	if err := proof.Validate(pp, u, w); err != nil {
+		return err
 	}
+	group := pp.group()
 	var c, l big.Int
-	transcript := fiatshamir.InitTranscript([]string{"ZKPoKE", pp.G.String(), pp.H.String(),
-		pp.N.String(), u.String(), w.String(), proof.z.String(), proof.Ag.String(), proof.Au.String()}, fiatshamir.Max252)
-	c.Set(transcript.GetIntChallengeUsingTranscript())
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	transcript := zkpokeTranscript(parent, pp, u, w, proof)
+	c.Set(transcript.IntChallenge("c"))
+	l.Set(transcript.PrimeChallenge("l"))
+	if proof.rx.Cmp(&l) >= 0 {
+		return malformed("ZKPoKE", "rx", "is not smaller than the challenge")
+	}
+	if proof.rrho.Cmp(&l) >= 0 {
+		return malformed("ZKPoKE", "rrho", "is not smaller than the challenge")
+	}
 
-	var lhs, rhs big.Int
 	// checking the fist condition
-	lhs.Exp(proof.Qg, &l, pp.N)
-	lhs.Mul(&lhs, MultiExp(pp.G, proof.rx, pp.H, proof.rrho, pp.N))
-	lhs.Mod(&lhs, pp.N)
-
-	rhs.Exp(proof.z, &c, pp.N)
-	rhs.Mul(&rhs, proof.Ag)
-	rhs.Mod(&rhs, pp.N)
-	if lhs.Cmp(&rhs) != 0 {
-		return false
+	lhs := group.MultiExp([]*big.Int{proof.Qg, pp.G, pp.H}, []*big.Int{&l, proof.rx, proof.rrho})
+	rhs := group.Mul(group.Exp(proof.z, &c), proof.Ag)
+	if !group.Equal(lhs, rhs) {
+		return challengeMismatch("ZKPoKE", "Qg^l * g^rx * h^rrho = z^c * Ag")
 	}
-	lhs.Set(MultiExp(proof.Qu, &l, u, proof.rx, pp.N))
-	rhs.Exp(w, &c, pp.N)
-	rhs.Mul(&rhs, proof.Au)
-	rhs.Mod(&rhs, pp.N)
-	return lhs.Cmp(&rhs) == 0
+	lhs = multiExp(group, proof.Qu, &l, u, proof.rx)
+	rhs = group.Mul(group.Exp(w, &c), proof.Au)
+	if !group.Equal(lhs, rhs) {
+		return challengeMismatch("ZKPoKE", "Qu^l * u^rx = w^c * Au")
+	}
+	return nil
 }
 
 // PoEProof contains the proofs for PoE
@@ -182,32 +263,88 @@ func (proof *PoEProof) isEmpty() bool {
 	return false
 }
 
-// PoEProve proves g^x = C
+// Validate checks that base, C and Q are group elements and that x is non-negative
+func (proof *PoEProof) Validate(group Group, base, C, x *big.Int) error {
+	v := newValidator("PoE", group)
+	if group == nil {
+		v.fail("group", "is missing")
+	}
+	v.element("base", base)
+	v.element("C", C)
+	v.nonNegative("x", x)
+	if v.present("proof", proof != nil) {
+		v.element("Q", proof.Q)
+	}
+	return v.result()
+}
+
+// PoEProve proves g^x = C in QR_N^+ for N = mod
 func PoEProve(base, mod, C, x *big.Int) (*PoEProof, error) {
+	return PoEProveInGroup(NewSignedQRGroup(mod), base, C, x)
+}
+
+// poeChallenge returns the prime challenge of a PoE proof of base^x = C in group continuing parent
+func poeChallenge(parent *fiatshamir.Transcript, group Group, base, C, x *big.Int) *big.Int {
+	transcript := newTranscript(parent, "PoE", group)
+	transcript.AppendInts("base", base)
+	transcript.AppendInts("C", C)
+	transcript.AppendInts("x", x)
+	return transcript.PrimeChallenge("l")
+}
+
+// PoEProveInGroup proves g^x = C in group
+func PoEProveInGroup(group Group, base, C, x *big.Int) (*PoEProof, error) {
+	return poeProve(nil, group, base, C, x)
+}
+
+// poeProve is PoEProveInGroup continuing parent
+func poeProve(parent *fiatshamir.Transcript, group Group, base, C, x *big.Int) (*PoEProof, error) {
 	var ret PoEProof
-	ret.Q = new(big.Int)
-	var temp, q, l big.Int
-	temp.Exp(base, x, mod)
-	if temp.Cmp(C) != 0 {
+	var q, l big.Int
+	if !group.Equal(group.Exp(base, x), C) {
 		return nil, errors.New("PoKEStar inputs a invalid statement")
 	}
 
-	transcript := fiatshamir.InitTranscript([]string{"PoE", base.String(), mod.String(), C.String(), x.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	l.Set(poeChallenge(parent, group, base, C, x))
 	q.Div(x, &l)
-	ret.Q.Exp(base, &q, mod)
+	ret.Q = group.Exp(base, &q)
 	return &ret, nil
 }
 
 // PoEVerify checks the proof, returns true if everything is good
 func PoEVerify(base, mod, C, x *big.Int, proof *PoEProof) bool {
-	if proof == nil || proof.isEmpty() {
-		return false
+	return PoEVerifyErr(base, mod, C, x, proof) == nil
+}
+
+// PoEVerifyErr checks the proof, returns nil if everything is good and otherwise the reason of the failure
+func PoEVerifyErr(base, mod, C, x *big.Int, proof *PoEProof) error {
+	if mod == nil || mod.Sign() <= 0 {
+		return malformed("PoE", "mod", "is not a positive modulus")
+	}
+	return PoEVerifyInGroupErr(NewSignedQRGroup(mod), base, C, x, proof)
+}
+
+// PoEVerifyInGroup checks the proof in group, returns true if everything is good
+func PoEVerifyInGroup(group Group, base, C, x *big.Int, proof *PoEProof) bool {
+	return PoEVerifyInGroupErr(group, base, C, x, proof) == nil
+}
+
+// PoEVerifyInGroupErr checks the proof in group, returns nil if everything is good and otherwise the reason of the
+// failure
+func PoEVerifyInGroupErr(group Group, base, C, x *big.Int, proof *PoEProof) error {
+	return poeVerify(nil, group, base, C, x, proof)
+}
+
+// poeVerify is PoEVerifyInGroupErr continuing parent
+func poeVerify(parent *fiatshamir.Transcript, group Group, base, C, x *big.Int, proof *PoEProof) error {
+	if err := proof.Validate(group, base, C, x); err != nil {
+		return err
 	}
-	var temp, l, r big.Int
-	transcript := fiatshamir.InitTranscript([]string{"PoE", base.String(), mod.String(), C.String(), x.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	var l, r big.Int
+	l.Set(poeChallenge(parent, group, base, C, x))
 	r.Mod(x, &l)
-	temp.Set(MultiExp(proof.Q, &l, base, &r, mod))
-	return temp.Cmp(C) == 0
+	if !group.Equal(multiExp(group, proof.Q, &l, base, &r), C) {
+		return challengeMismatch("PoE", "Q^l * base^(x mod l) = C")
+	}
+	return nil
 }
@@ -23,74 +23,125 @@ func (proof *ZKPoKEModProof) isEmpty() bool {
 	return false
 }
 
+// Validate checks that C, D and Q are group elements, that xmod is in [0, n), that r can be a remainder modulo l*n
+// and that the sub-proof is present
+func (proof *ZKPoKEModProof) Validate(pp *PublicParameters, C, n, xmod *big.Int) error {
+	v := newValidator("ZKPoKEMod", nil)
+	v.parameters(pp)
+	v.element("C", C)
+	v.positive("n", n)
+	if v.err == nil {
+		v.below("xmod", xmod, n)
+	}
+	if v.present("proof", proof != nil) {
+		v.element("D", proof.D)
+		v.element("Q", proof.Q)
+		v.remainder("r", proof.r, maxChallengeBits+n.BitLen())
+		v.present("pi", proof.pi != nil)
+	}
+	return v.result()
+}
+
+// zkpokeModTranscript returns the transcript of a ZKPoKEMod proof continuing parent after its statement, the proof pi
+// of the blinding commitment is forked from it
+func zkpokeModTranscript(parent *fiatshamir.Transcript, pp *PublicParameters,
+	C, n, xmod *big.Int) *fiatshamir.Transcript {
+	transcript := newTranscript(parent, "ZKPoKEMod", pp.group())
+	transcript.AppendInts("G", pp.G)
+	transcript.AppendInts("C", C)
+	transcript.AppendInts("n", n)
+	transcript.AppendInts("xmod", xmod)
+	return transcript
+}
+
 func ZKPoKEModProve(pp *PublicParameters, C, x, n, xmod *big.Int) (*ZKPoKEModProof, error) {
+	return zkpokeModProve(nil, pp, C, x, n, xmod)
+}
+
+// zkpokeModProve is ZKPoKEModProve continuing parent
+func zkpokeModProve(parent *fiatshamir.Transcript, pp *PublicParameters,
+	C, x, n, xmod *big.Int) (*ZKPoKEModProof, error) {
+	group := pp.group()
 	// input checks
 	var temp big.Int
 	temp.Mod(x, n)
 	if temp.Cmp(xmod) != 0 {
 		return nil, errors.New("ZKPoKEModN inputs a invalid statement")
 	}
-	temp.Exp(pp.G, x, pp.N)
-	if temp.Cmp(C) != 0 {
+	if !group.Equal(pp.expG(x), C) {
 		return nil, errors.New("ZKPoKEModN inputs a invalid statement")
 	}
 
 	var ret ZKPoKEModProof
 
-	b := new(big.Int).Set(pp.N)
+	b := new(big.Int).Set(group.Bound())
 	lsh := 2*securityPara - 2
 	b.Lsh(b, uint(lsh))
 	m, err := rand.Int(rand.Reader, b)
 	if err != nil {
 		return nil, err
 	}
-	ret.D = new(big.Int).Exp(pp.G, m, pp.N)
-	proof, err := PoKEStarProve(pp, ret.D, m)
+	ret.D = pp.expG(m)
+	transcript := zkpokeModTranscript(parent, pp, C, n, xmod)
+	proof, err := pokeStarProve(transcript.Fork("pi"), pp, ret.D, m)
 	if err != nil {
 		return nil, err
 	}
 	ret.pi = proof
 
 	var l big.Int
-	transcript := fiatshamir.InitTranscript([]string{"ZKPoKEMod", pp.G.String(), pp.N.String(),
-		C.String(), n.String(), xmod.String(), ret.pi.Q.String(), ret.pi.R.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	transcript.AppendInts("D", ret.D)
+	transcript.AppendInts("pi", ret.pi.Q, ret.pi.R)
+	l.Set(transcript.PrimeChallenge("l"))
 
 	var exp, q, r big.Int //exp = x + mn
 	exp.Mul(m, n)
 	exp.Add(&exp, x)
 	temp.Mul(&l, n) //temp = l*n
 	q.DivMod(&exp, &temp, &r)
-	ret.Q = new(big.Int).Exp(pp.G, &q, pp.N)
+	ret.Q = pp.expG(&q)
 	ret.r = new(big.Int).Set(&r)
 	return &ret, nil
 }
 
 func ZKPoKEModVerify(pp *PublicParameters, C, n, xmod *big.Int, proof *ZKPoKEModProof) bool {
-	if proof == nil || proof.isEmpty() {
-		return false
+	return ZKPoKEModVerifyErr(pp, C, n, xmod, proof) == nil
+}
+
+// ZKPoKEModVerifyErr checks the proof, returns nil if everything is good and otherwise the reason of the failure
+func ZKPoKEModVerifyErr(pp *PublicParameters, C, n, xmod *big.Int, proof *ZKPoKEModProof) error {
+	return zkpokeModVerify(nil, pp, C, n, xmod, proof)
+}
+
+// zkpokeModVerify is ZKPoKEModVerifyErr continuing parent
+func zkpokeModVerify(parent *fiatshamir.Transcript, pp *PublicParameters, C, n, xmod *big.Int,
+	proof *ZKPoKEModProof) error {
+	if err := proof.Validate(pp, C, n, xmod); err != nil {
+		return err
 	}
-	flag := PoKEStarVerify(pp, proof.D, proof.pi)
-	if !flag {
-		return false
+	transcript := zkpokeModTranscript(parent, pp, C, n, xmod)
+	if err := pokeStarVerify(transcript.Fork("pi"), pp, proof.D, proof.pi); err != nil {
+		return subproofFailed("ZKPoKEMod", "pi", err)
 	}
+	group := pp.group()
 	var l big.Int
-	transcript := fiatshamir.InitTranscript([]string{"ZKPoKEMod", pp.G.String(), pp.N.String(),
-		C.String(), n.String(), xmod.String(), proof.pi.Q.String(), proof.pi.R.String()}, fiatshamir.Max252)
-	l.Set(transcript.GetPrimeChallengeUsingTranscript())
+	transcript.AppendInts("D", proof.D)
+	transcript.AppendInts("pi", proof.pi.Q, proof.pi.R)
+	l.Set(transcript.PrimeChallenge("l"))
 
-	var temp, lhs, rhs big.Int
+	var temp big.Int
 	temp.Mul(&l, n) //temp = l*n
 	if temp.Cmp(proof.r) != 1 {
-		return false
+		return malformed("ZKPoKEMod", "r", "is not smaller than l*n")
 	}
-	lhs.Set(MultiExp(proof.Q, &temp, pp.G, proof.r, pp.N))
-	rhs.Exp(proof.D, n, pp.N)
-	rhs.Mul(&rhs, C)
-	rhs.Mod(&rhs, pp.N)
-	if lhs.Cmp(&rhs) != 0 {
-		return false
+	lhs := multiExp(group, proof.Q, &temp, pp.G, proof.r)
+	rhs := group.Mul(group.Exp(proof.D, n), C)
+	if !group.Equal(lhs, rhs) {
+		return challengeMismatch("ZKPoKEMod", "Q^(l*n) * g^r = D^n * C")
 	}
 	temp.Mod(proof.r, n)
-	return temp.Cmp(xmod) == 0
+	if temp.Cmp(xmod) != 0 {
+		return challengeMismatch("ZKPoKEMod", "r mod n = xmod")
+	}
+	return nil
 }
@@ -3,6 +3,8 @@ package protocol
 import (
 	"crypto/rand"
 	"math/big"
+
+	fiatshamir "github.com/VTLP/fiat-shamir"
 )
 
 // ZKPoMoDE contains the proofs for PoMoDE: proof of modular double exponent
@@ -22,38 +24,87 @@ func (proof *ZKPoMoDEProof) isEmpty() bool {
 	return false
 }
 
+// Validate checks that C, D and C2 are group elements, that n and e are positive, that xmod is in [0, n) and that
+// every sub-proof is present
+func (proof *ZKPoMoDEProof) Validate(pp *PublicParameters, C, n, e, xmod *big.Int) error {
+	v := newValidator("ZKPoMoDE", nil)
+	v.parameters(pp)
+	v.element("C", C)
+	validateModulus(v, n, e, xmod)
+	if v.present("proof", proof != nil) {
+		v.element("D", proof.D)
+		v.element("C2", proof.C2)
+		v.present("pi1", proof.pi1 != nil)
+		v.present("pi2", proof.pi2 != nil)
+		v.present("pi3", proof.pi3 != nil)
+	}
+	return v.result()
+}
+
+// validateModulus checks the modulus n, the exponent e and the residue xmod of a PoMoDE statement
+func validateModulus(v *validator, n, e, xmod *big.Int) {
+	v.positive("n", n)
+	v.positive("e", e)
+	if v.err == nil {
+		v.below("xmod", xmod, n)
+	}
+}
+
+// pomodeTranscript returns the transcript of a proof of modular double exponent continuing parent after its statement,
+// the sub-proofs are forked from it
+func pomodeTranscript(parent *fiatshamir.Transcript, protocol string, pp *PublicParameters, n, e, xmod *big.Int,
+	C ...*big.Int) *fiatshamir.Transcript {
+	transcript := newTranscript(parent, protocol, pp.group())
+	transcript.AppendInts("G", pp.G)
+	transcript.AppendInts("H", pp.H)
+	transcript.AppendInts("C", C...)
+	transcript.AppendInts("n", n)
+	transcript.AppendInts("e", e)
+	transcript.AppendInts("xmod", xmod)
+	return transcript
+}
+
 func ZKPoMoDEProve(pp *PublicParameters, C, n, e, xmod, x *big.Int) (*ZKPoMoDEProof, error) {
+	return zkpomodeProve(nil, pp, C, n, e, xmod, x)
+}
+
+// zkpomodeProve is ZKPoMoDEProve continuing parent
+func zkpomodeProve(parent *fiatshamir.Transcript, pp *PublicParameters,
+	C, n, e, xmod, x *big.Int) (*ZKPoMoDEProof, error) {
+	group := pp.group()
 	var ret ZKPoMoDEProof
-	length := 2048 + 256
-	var b, sum, sum2e, temp big.Int
+	length := blindingLength(group)
+	var b, sum, sum2e big.Int
 	b.SetInt64(1)
 	b.Lsh(&b, uint(length))
 	m, err := rand.Int(rand.Reader, &b)
 	if err != nil {
 		return nil, err
 	}
-	ret.D = new(big.Int).Exp(pp.G, m, pp.N)
-	proof, err := PoKEStarProve(pp, ret.D, m)
+	ret.D = pp.expG(m)
+	transcript := pomodeTranscript(parent, "ZKPoMoDE", pp, n, e, xmod, C)
+	proof, err := pokeStarProve(transcript.Fork("pi1"), pp, ret.D, m)
 	if err != nil {
 		return nil, err
 	}
 	ret.pi1 = proof
+	transcript.AppendInts("D", ret.D)
+	transcript.AppendInts("pi1", ret.pi1.Q, ret.pi1.R)
 
 	// sum = mn + x, sum2e = sum^e temp = C*D^n
 	sum.Mul(m, n)
 	sum.Add(&sum, x)
 	sum2e.Exp(&sum, e, nil)
-	temp.Exp(ret.D, n, pp.N)
-	temp.Mul(&temp, C)
-	temp.Mod(&temp, pp.N)
-	ret.C2 = new(big.Int).Exp(pp.G, &sum2e, pp.N)
-	tempProof1, err := ZKPoKDEProve(pp, &temp, ret.C2, &sum, e)
+	temp := group.Mul(group.Exp(ret.D, n), C)
+	ret.C2 = pp.expG(&sum2e)
+	transcript.AppendInts("C2", ret.C2)
+	tempProof1, err := zkpokdeProve(transcript.Fork("pi2"), pp, temp, ret.C2, &sum, e)
 	if err != nil {
 		return nil, err
 	}
 	ret.pi2 = tempProof1
 
-	tempProof2, err := ZKPoKEModProve(pp, ret.C2, &sum2e, n, xmod)
+	tempProof2, err := zkpokeModProve(transcript.Fork("pi3"), pp, ret.C2, &sum2e, n, xmod)
 	if err != nil {
 		return nil, err
 	}
@@ -62,25 +113,36 @@ func ZKPoMoDEProve(pp *PublicParameters, C, n, e, xmod, x *big.Int) (*ZKPoMoDEPr
 }
 
 func ZKPoMoDEVerify(pp *PublicParameters, C, n, e, xmod *big.Int, proof *ZKPoMoDEProof) bool {
-	if proof == nil || proof.isEmpty() {
-		return false
+	return ZKPoMoDEVerifyErr(pp, C, n, e, xmod, proof) == nil
+}
+
+// ZKPoMoDEVerifyErr checks the proof, returns nil if everything is good and otherwise the reason of the failure, a
+// failed sub-proof is reported as a *SubproofError
+func ZKPoMoDEVerifyErr(pp *PublicParameters, C, n, e, xmod *big.Int, proof *ZKPoMoDEProof) error {
+	return zkpomodeVerify(nil, pp, C, n, e, xmod, proof)
+}
+
+// zkpomodeVerify is ZKPoMoDEVerifyErr continuing parent
+func zkpomodeVerify(parent *fiatshamir.Transcript, pp *PublicParameters, C, n, e, xmod *big.Int,
+	proof *ZKPoMoDEProof) error {
+	if err := proof.Validate(pp, C, n, e, xmod); err != nil {
+		return err
 	}
-	if !PoKEStarVerify(pp, proof.D, proof.pi1) {
-		return false
+	transcript := pomodeTranscript(parent, "ZKPoMoDE", pp, n, e, xmod, C)
+	if err := pokeStarVerify(transcript.Fork("pi1"), pp, proof.D, proof.pi1); err != nil {
+		return subproofFailed("ZKPoMoDE", "pi1", err)
 	}
+	transcript.AppendInts("D", proof.D)
+	transcript.AppendInts("pi1", proof.pi1.Q, proof.pi1.R)
+	transcript.AppendInts("C2", proof.C2)
 	// temp = C*D^n
-	var temp big.Int
-	temp.Exp(proof.D, n, pp.N)
-	temp.Mul(&temp, C)
-	temp.Mod(&temp, pp.N)
-	if !ZKPoKDEVerify(pp, &temp, proof.C2, e, proof.pi2) {
-		return false
+	group := pp.group()
+	temp := group.Mul(group.Exp(proof.D, n), C)
+	if err := zkpokdeVerify(transcript.Fork("pi2"), pp, temp, proof.C2, e, proof.pi2); err != nil {
+		return subproofFailed("ZKPoMoDE", "pi2", err)
 	}
 
-	if !ZKPoKEModVerify(pp, proof.C2, n, xmod, proof.pi3) {
-		return false
-	}
-	return true
+	return subproofFailed("ZKPoMoDE", "pi3", zkpokeModVerify(transcript.Fork("pi3"), pp, proof.C2, n, xmod, proof.pi3))
 }
 
 // ZKPoMoDE contains the proofs for PoMoDE: proof of modular double exponent
@@ -96,15 +158,37 @@ func (proof *ZKPoMoDEFastProof) isEmpty() bool {
 	return false
 }
 
+// Validate checks that C1 and C2 are group elements, that n and e are positive, that xmod is in [0, n) and that every
+// sub-proof is present
+func (proof *ZKPoMoDEFastProof) Validate(pp *PublicParameters, C1, C2, n, e, xmod *big.Int) error {
+	v := newValidator("ZKPoMoDEFast", nil)
+	v.parameters(pp)
+	v.element("C1", C1)
+	v.element("C2", C2)
+	validateModulus(v, n, e, xmod)
+	if v.present("proof", proof != nil) {
+		v.present("pi1", proof.pi1 != nil)
+		v.present("pi2", proof.pi2 != nil)
+	}
+	return v.result()
+}
+
 func ZKPoMoDEFastProve(pp *PublicParameters, C1, C2, n, e, xmod, x *big.Int) (*ZKPoMoDEFastProof, error) {
+	return zkpomodeFastProve(nil, pp, C1, C2, n, e, xmod, x)
+}
+
+// zkpomodeFastProve is ZKPoMoDEFastProve continuing parent
+func zkpomodeFastProve(parent *fiatshamir.Transcript, pp *PublicParameters,
+	C1, C2, n, e, xmod, x *big.Int) (*ZKPoMoDEFastProof, error) {
 	var ret ZKPoMoDEFastProof
-	tempProof1, err := ZKPoKDEProve(pp, C1, C2, x, e)
+	transcript := pomodeTranscript(parent, "ZKPoMoDEFast", pp, n, e, xmod, C1, C2)
+	tempProof1, err := zkpokdeProve(transcript.Fork("pi1"), pp, C1, C2, x, e)
 	if err != nil {
 		return nil, err
 	}
 	ret.pi1 = tempProof1
 
-	tempProof2, err := ZKPoKEModProve(pp, C2, new(big.Int).Exp(x, e, nil), n, xmod)
+	tempProof2, err := zkpokeModProve(transcript.Fork("pi2"), pp, C2, new(big.Int).Exp(x, e, nil), n, xmod)
 	if err != nil {
 		return nil, err
 	}
@@ -113,14 +197,24 @@ func ZKPoMoDEFastProve(pp *PublicParameters, C1, C2, n, e, xmod, x *big.Int) (*Z
 }
 
 func ZKPoMoDEFastVerify(pp *PublicParameters, C1, C2, n, e, xmod *big.Int, proof *ZKPoMoDEFastProof) bool {
-	if proof == nil || proof.isEmpty() {
-		return false
-	}
-	if !ZKPoKDEVerify(pp, C1, C2, e, proof.pi1) {
-		return false
+	return ZKPoMoDEFastVerifyErr(pp, C1, C2, n, e, xmod, proof) == nil
+}
+
+// ZKPoMoDEFastVerifyErr checks the proof, returns nil if everything is good and otherwise the reason of the failure, a
+// failed sub-proof is reported as a *SubproofError
+func ZKPoMoDEFastVerifyErr(pp *PublicParameters, C1, C2, n, e, xmod *big.Int, proof *ZKPoMoDEFastProof) error {
+	return zkpomodeFastVerify(nil, pp, C1, C2, n, e, xmod, proof)
+}
+
+// zkpomodeFastVerify is ZKPoMoDEFastVerifyErr continuing parent
+func zkpomodeFastVerify(parent *fiatshamir.Transcript, pp *PublicParameters, C1, C2, n, e, xmod *big.Int,
+	proof *ZKPoMoDEFastProof) error {
+	if err := proof.Validate(pp, C1, C2, n, e, xmod); err != nil {
+		return err
 	}
-	if !ZKPoKEModVerify(pp, C2, n, xmod, proof.pi2) {
-		return false
+	transcript := pomodeTranscript(parent, "ZKPoMoDEFast", pp, n, e, xmod, C1, C2)
+	if err := zkpokdeVerify(transcript.Fork("pi1"), pp, C1, C2, e, proof.pi1); err != nil {
+		return subproofFailed("ZKPoMoDEFast", "pi1", err)
 	}
-	return true
+	return subproofFailed("ZKPoMoDEFast", "pi2", zkpokeModVerify(transcript.Fork("pi2"), pp, C2, n, xmod, proof.pi2))
 }
@@ -1,16 +1,29 @@
 package protocol
 
 import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
 	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
 )
 
 func TestPoKEStar(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var exponent, C big.Int
 	exponent.SetInt64(666)
-	C.Exp(setup.G, &exponent, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &exponent))
 	proof, err := PoKEStarProve(&pp, &C, &exponent)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -25,13 +38,13 @@ func TestPoKEStar(t *testing.T) {
 	if err == nil {
 		t.Errorf("error empty when it should not for TestPoKEStar")
 	}
-	C.Exp(setup.G, &exponent, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &exponent))
 	proof, err = PoKEStarProve(&pp, &C, &exponent)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
 	}
 	exponent.SetInt64(66777)
-	C.Exp(setup.G, &exponent, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &exponent))
 	flag = PoKEStarVerify(&pp, &C, proof)
 	if flag == true {
 		t.Errorf("pass verification when it should not")
@@ -40,10 +53,10 @@ func TestPoKEStar(t *testing.T) {
 
 func TestZKPoKE(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var exponent, C big.Int
 	exponent.SetInt64(666)
-	C.Exp(setup.G, &exponent, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &exponent))
 	proof, err := ZKPoKEProve(&pp, pp.G, &exponent, &C)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -58,13 +71,13 @@ func TestZKPoKE(t *testing.T) {
 	if err == nil {
 		t.Errorf("error empty when it should not for TestPoKEStar")
 	}
-	C.Exp(setup.G, &exponent, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &exponent))
 	proof, err = ZKPoKEProve(&pp, pp.G, &exponent, &C)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
 	}
 	exponent.SetInt64(66777)
-	C.Exp(setup.G, &exponent, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &exponent))
 	flag = ZKPoKEVerify(&pp, pp.G, &C, proof)
 	if flag == true {
 		t.Errorf("pass verification when it should not")
@@ -73,12 +86,12 @@ func TestZKPoKE(t *testing.T) {
 
 func TestZKPoKEMod(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C, n, xmod big.Int //x mod n = xmod
 	x.SetInt64(666)
 	n.SetInt64(10)
 	xmod.SetInt64(6)
-	C.Exp(setup.G, &x, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
 	proof, err := ZKPoKEModProve(&pp, &C, &x, &n, &xmod)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -93,13 +106,13 @@ func TestZKPoKEMod(t *testing.T) {
 	if err == nil {
 		t.Errorf("error empty when it should not for TestPoKEStar")
 	}
-	C.Exp(setup.G, &x, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
 	proof, err = ZKPoKEModProve(&pp, &C, &x, &n, &xmod)
 	if err == nil {
 		t.Errorf("error empty when it should not for TestPoKEStar")
 	}
 	x.SetInt64(66777)
-	C.Exp(setup.G, &x, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
 	flag = ZKPoKEModVerify(&pp, &C, &n, &xmod, proof)
 	if flag == true {
 		t.Errorf("pass verification when it should not")
@@ -108,10 +121,10 @@ func TestZKPoKEMod(t *testing.T) {
 
 func TestPoE(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var exponent, C big.Int
 	exponent.SetInt64(666)
-	C.Exp(setup.G, &exponent, setup.N)
+	C.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &exponent))
 	proof, err := PoEProve(pp.G, pp.N, &C, &exponent)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -130,13 +143,13 @@ func TestPoE(t *testing.T) {
 
 func TestPoKDE(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, xe big.Int //xe = x^e
 	x.SetInt64(666)
 	e.SetInt64(17)
 	xe.Exp(&x, &e, nil)
-	C1.Exp(setup.G, &x, setup.N)
-	C2.Exp(setup.G, &xe, setup.N)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &xe))
 	proof, err := PoKDEProve(&pp, &C1, &C2, &x, &e)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -147,7 +160,7 @@ func TestPoKDE(t *testing.T) {
 	}
 
 	x.SetInt64(66777)
-	C2.Exp(setup.G, &x, setup.N)
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
 	flag = PoKDEVerify(&pp, &C1, &C2, &e, proof)
 	if flag == true {
 		t.Errorf("pass verification when it should not")
@@ -156,13 +169,13 @@ func TestPoKDE(t *testing.T) {
 
 func TestZKPoKDE(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, xe big.Int //xe = x^e
 	x.SetInt64(666)
 	e.SetInt64(17)
 	xe.Exp(&x, &e, nil)
-	C1.Exp(setup.G, &x, setup.N)
-	C2.Exp(setup.G, &xe, setup.N)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &xe))
 	proof, err := ZKPoKDEProve(&pp, &C1, &C2, &x, &e)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -173,7 +186,7 @@ func TestZKPoKDE(t *testing.T) {
 	}
 
 	x.SetInt64(66777)
-	C2.Exp(setup.G, &x, setup.N)
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
 	flag = ZKPoKDEVerify(&pp, &C1, &C2, &e, proof)
 	if flag == true {
 		t.Errorf("pass verification when it should not")
@@ -182,15 +195,15 @@ func TestZKPoKDE(t *testing.T) {
 
 func TestZKPoMoDE(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, x2e, n, xmod big.Int //x2e = x^e
 	x.SetInt64(6)
 	e.SetInt64(7)
 	n.SetInt64(10)
 	x2e.Exp(&x, &e, nil) //6^7 = 279936
 	xmod.Mod(&x2e, &n)
-	C1.Exp(setup.G, &x, setup.N)
-	C2.Exp(setup.G, &x2e, setup.N)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x2e))
 	proof, err := ZKPoMoDEProve(&pp, &C1, &n, &e, &xmod, &x)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -201,7 +214,7 @@ func TestZKPoMoDE(t *testing.T) {
 	}
 
 	x.SetInt64(66777)
-	C1.Exp(setup.G, &x, setup.N)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
 	flag = ZKPoMoDEVerify(&pp, &C1, &n, &e, &xmod, proof)
 	if flag == true {
 		t.Errorf("pass verification when it should not")
@@ -210,15 +223,15 @@ func TestZKPoMoDE(t *testing.T) {
 
 func TestZKPoMoDEFast(t *testing.T) {
 	setup := TrustedSetup()
-	pp := PublicParameters{setup.N, setup.G, setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
 	var x, C1, C2, e, x2e, n, xmod big.Int //xe = x^e
 	x.SetInt64(6)
 	e.SetInt64(7)
 	n.SetInt64(10)
 	x2e.Exp(&x, &e, nil) //6^7 = 279936
 	xmod.SetInt64(6)
-	C1.Exp(setup.G, &x, setup.N)
-	C2.Exp(setup.G, &x2e, setup.N)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x2e))
 	proof, err := ZKPoMoDEFastProve(&pp, &C1, &C2, &n, &e, &xmod, &x)
 	if err != nil {
 		t.Errorf("error not empty for TestPoKEStar")
@@ -229,9 +242,1697 @@ func TestZKPoMoDEFast(t *testing.T) {
 	}
 
 	x.SetInt64(66777)
-	C1.Exp(setup.G, &x, setup.N)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
 	flag = ZKPoMoDEFastVerify(&pp, &C1, &C2, &n, &e, &xmod, proof)
 	if flag == true {
 		t.Errorf("pass verification when it should not")
 	}
 }
+
+func TestSubproofBinding(t *testing.T) {
+	setup := TrustedSetup()
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	var x, C1, C2, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x2e))
+	proof, err := ZKPoMoDEFastProve(&pp, &C1, &C2, &n, &e, &xmod, &x)
+	if err != nil {
+		t.Fatalf("error not empty for ZKPoMoDEFastProve: %v", err)
+	}
+
+	// the sub-proofs are bound to the composite proof and fail on their own
+	if ZKPoKDEVerify(&pp, &C1, &C2, &e, proof.pi1) {
+		t.Errorf("sub-proof pi1 passes verification on its own")
+	}
+	if PoKEStarVerify(&pp, proof.pi1.D, proof.pi1.pi1) {
+		t.Errorf("nested sub-proof pi1.pi1 passes verification on its own")
+	}
+	if ZKPoKEModVerify(&pp, &C2, &n, &xmod, proof.pi2) {
+		t.Errorf("sub-proof pi2 passes verification on its own")
+	}
+
+	// they only pass in the transcript of the statement they were made for
+	transcript := pomodeTranscript(nil, "ZKPoMoDEFast", &pp, &n, &e, &xmod, &C1, &C2)
+	if err := zkpokdeVerify(transcript.Fork("pi1"), &pp, &C1, &C2, &e, proof.pi1); err != nil {
+		t.Errorf("sub-proof pi1 fails in its transcript: %v", err)
+	}
+	if zkpokdeVerify(transcript.Fork("pi2"), &pp, &C1, &C2, &e, proof.pi1) == nil {
+		t.Errorf("sub-proof pi1 passes under another label")
+	}
+	var other big.Int
+	other.Add(&xmod, big.NewInt(n.Int64()))
+	transcript = pomodeTranscript(nil, "ZKPoMoDEFast", &pp, &n, &e, &other, &C1, &C2)
+	if zkpokdeVerify(transcript.Fork("pi1"), &pp, &C1, &C2, &e, proof.pi1) == nil {
+		t.Errorf("sub-proof pi1 passes in the transcript of another statement")
+	}
+
+	// a proof made on its own can not be grafted into a composite proof
+	standalone, err := ZKPoKDEProve(&pp, &C1, &C2, &x, &e)
+	if err != nil {
+		t.Fatalf("error not empty for ZKPoKDEProve: %v", err)
+	}
+	grafted := *proof
+	grafted.pi1 = standalone
+	err = ZKPoMoDEFastVerifyErr(&pp, &C1, &C2, &n, &e, &xmod, &grafted)
+	var subErr *SubproofError
+	if !errors.As(err, &subErr) {
+		t.Errorf("grafted proof is not rejected as a failed sub-proof: %v", err)
+	}
+}
+
+func TestPuzzleTimeParameter(t *testing.T) {
+	rsasetup := RSAExpSetup()
+	s := GenVRFSolution([]byte("VTLP test message"), rsasetup)
+	qr := NewSignedQRGroup(rsasetup.RSAMod)
+	want := qr.Element(s)
+	for _, T := range []int64{1, 2, 17, 1000} {
+		puzzle, err := rsasetup.NewPuzzle(s, T)
+		if err != nil {
+			t.Fatalf("error not empty for NewPuzzle with T = %d", T)
+		}
+		if puzzle.T != T || puzzle.N.Cmp(rsasetup.RSAMod) != 0 {
+			t.Errorf("wrong puzzle parameters for T = %d", T)
+		}
+		if rsasetup.Solution(puzzle).Cmp(want) != 0 {
+			t.Errorf("wrong trapdoor solution for T = %d", T)
+		}
+		var y big.Int
+		y.Set(puzzle.Z)
+		for i := int64(0); i < T; i++ {
+			y.Mul(&y, &y)
+			y.Mod(&y, puzzle.N)
+		}
+		if qr.Element(&y).Cmp(want) != 0 {
+			t.Errorf("T squarings do not solve the puzzle for T = %d", T)
+		}
+	}
+	if rsasetup.TimeExponent(TimePara).Cmp(rsasetup.Exponent) != 0 {
+		t.Errorf("cached time exponent does not match Exponent")
+	}
+	exp := rsasetup.TimeExponent(17)
+	exp.SetInt64(0)
+	if rsasetup.TimeExponent(17).Cmp(new(big.Int).Exp(big2, big.NewInt(17), rsasetup.Order)) != 0 {
+		t.Errorf("cached time exponent modified by the caller")
+	}
+	// concurrent callers share the cache of every T
+	var wg sync.WaitGroup
+	exps := make([]*big.Int, 16)
+	for i := range exps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			exps[i] = rsasetup.TimeExponent(int64(100 + i%4))
+		}(i)
+	}
+	wg.Wait()
+	for i, exp := range exps {
+		if exp.Cmp(new(big.Int).Exp(big2, big.NewInt(int64(100+i%4)), rsasetup.Order)) != 0 {
+			t.Errorf("wrong time exponent for T = %d from a concurrent caller", 100+i%4)
+		}
+	}
+
+	if _, err := rsasetup.NewPuzzle(s, 0); err == nil {
+		t.Errorf("error empty for a non-positive time parameter")
+	}
+	var negated big.Int
+	negated.Sub(rsasetup.RSAMod, s)
+	if puzzle, err := rsasetup.NewPuzzle(&negated, 10); err != nil || rsasetup.Solution(puzzle).Cmp(want) != 0 {
+		t.Errorf("puzzle for -s does not have the solution |s|")
+	}
+	nonQR := big.NewInt(2)
+	for big.Jacobi(nonQR, rsasetup.RSAMod) != -1 {
+		nonQR.Add(nonQR, big1)
+	}
+	if _, err := rsasetup.NewPuzzle(nonQR, 10); err == nil {
+		t.Errorf("error empty for a quadratic non-residue")
+	}
+}
+
+func TestSolvePuzzle(t *testing.T) {
+	rsasetup := RSAExpSetup()
+	s := NewSignedQRGroup(rsasetup.RSAMod).Element(GenVRFSolution([]byte("VTLP test message"), rsasetup))
+	puzzle, err := rsasetup.NewPuzzle(s, 5000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	var reports []int64
+	opts := SolveOptions{
+		Progress:         func(done, total int64) { reports = append(reports, done) },
+		ProgressInterval: 1000,
+	}
+	ret, err := SolvePuzzle(context.Background(), puzzle, &opts)
+	if err != nil || ret.Cmp(s) != 0 {
+		t.Errorf("SolvePuzzle returns a wrong solution")
+	}
+	if len(reports) != 5 || reports[0] != 1000 || reports[4] != 5000 {
+		t.Errorf("wrong progress reports %v", reports)
+	}
+
+	// cancel in the middle, then resume from the checkpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	path := filepath.Join(t.TempDir(), "puzzle.checkpoint")
+	reports = nil
+	opts = SolveOptions{
+		Progress: func(done, total int64) {
+			reports = append(reports, done)
+			if done == 2*ctxCheckInterval {
+				cancel()
+			}
+		},
+		ProgressInterval: ctxCheckInterval,
+		CheckpointPath:   path,
+	}
+	_, err = SolvePuzzle(ctx, puzzle, &opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("SolvePuzzle is not cancelled")
+	}
+	// the values of checkpoints before signedPuzzleVersion are in Z_N^*
+	checkpoint, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error not empty for reading the checkpoint")
+	}
+	old := append([]byte{}, checkpoint...)
+	old[0] = signedPuzzleVersion - 1
+	if err = os.WriteFile(path, old, 0o600); err != nil {
+		t.Fatalf("error not empty for writing the checkpoint")
+	}
+	if _, err = SolvePuzzle(context.Background(), puzzle, &opts); err == nil {
+		t.Errorf("SolvePuzzle resumes a checkpoint of version %d", old[0])
+	}
+	if err = os.WriteFile(path, checkpoint, 0o600); err != nil {
+		t.Fatalf("error not empty for writing the checkpoint")
+	}
+	reports = nil
+	ret, err = SolvePuzzle(context.Background(), puzzle, &opts)
+	if err != nil || ret.Cmp(s) != 0 {
+		t.Errorf("SolvePuzzle returns a wrong solution after resuming")
+	}
+	if len(reports) == 0 || reports[0] != 3*ctxCheckInterval {
+		t.Errorf("SolvePuzzle does not resume from the checkpoint, reports %v", reports)
+	}
+
+	another, err := rsasetup.NewPuzzle(s, 4000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	if _, err = SolvePuzzle(context.Background(), another, &opts); !errors.Is(err, errCheckpointMismatch) {
+		t.Errorf("SolvePuzzle resumes the checkpoint of another puzzle")
+	}
+	if _, err = SolvePuzzle(context.Background(), &Puzzle{N: puzzle.N, Z: puzzle.Z}, nil); err == nil {
+		t.Errorf("error empty for a non-positive time parameter")
+	}
+}
+
+func TestVDF(t *testing.T) {
+	rsasetup := RSAExpSetup()
+	s := NewSignedQRGroup(rsasetup.RSAMod).Element(GenVRFSolution([]byte("VTLP test message"), rsasetup))
+	for _, vdf := range []VDF{WesolowskiVDF{}, PietrzakVDF{}} {
+		for _, T := range []int64{1, 2, 3, 1000, 3 * maxProofPoints, 3*maxProofPoints + 5} {
+			puzzle, err := rsasetup.NewPuzzle(s, T)
+			if err != nil {
+				t.Fatalf("error not empty for NewPuzzle")
+			}
+			y, proof, err := SolvePuzzleWithProof(context.Background(), puzzle, &SolveOptions{VDF: vdf})
+			if err != nil || y.Cmp(s) != 0 {
+				t.Fatalf("SolvePuzzleWithProof returns a wrong solution for %T, T = %d", vdf, T)
+			}
+			if !vdf.Verify(puzzle, y, proof) {
+				t.Errorf("did not pass verification for %T, T = %d", vdf, T)
+			}
+			var tampered big.Int
+			tampered.Add(y, big1)
+			if vdf.Verify(puzzle, &tampered, proof) {
+				t.Errorf("pass verification with a wrong solution for %T, T = %d", vdf, T)
+			}
+			if vdf.Verify(&Puzzle{N: puzzle.N, T: T + 1, Z: puzzle.Z}, y, proof) {
+				t.Errorf("pass verification with a wrong time parameter for %T, T = %d", vdf, T)
+			}
+		}
+	}
+
+	puzzle, err := rsasetup.NewPuzzle(s, 1000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	y, proof, err := SolvePuzzleWithProof(context.Background(), puzzle, nil)
+	if err != nil {
+		t.Fatalf("error not empty for SolvePuzzleWithProof")
+	}
+	wesolowski := proof.(*WesolowskiProof)
+	var tampered big.Int
+	tampered.Mul(wesolowski.Pi, big2)
+	tampered.Mod(&tampered, puzzle.N)
+	if WesolowskiVerify(puzzle, y, &WesolowskiProof{Pi: &tampered}) {
+		t.Errorf("pass verification with a tampered Wesolowski proof")
+	}
+	// (-Pi)^l * Z^r = -y for an odd l, so the sign-flipped proof for the wrong solution -y passes in Z_N^*
+	inZN, err := NewGroupPuzzle(NewRSAGroup(puzzle.N), puzzle.Z, puzzle.T)
+	if err != nil {
+		t.Fatalf("error not empty for NewGroupPuzzle")
+	}
+	var negated big.Int
+	negated.Sub(puzzle.N, inZN.group().Exp(puzzle.Z, new(big.Int).Lsh(big1, uint(puzzle.T))))
+	flipped, err := WesolowskiProve(inZN, &negated, []*big.Int{puzzle.Z}, puzzle.T)
+	if err != nil {
+		t.Fatalf("error not empty for WesolowskiProve")
+	}
+	flipped.Pi.Sub(puzzle.N, flipped.Pi)
+	if !WesolowskiVerify(inZN, &negated, flipped) {
+		t.Errorf("sign-flipped Wesolowski proof does not pass in Z_N^*")
+	}
+	// in QR_N^+ neither -y nor -Pi is an element
+	negated.Sub(puzzle.N, y)
+	if flipped, err = WesolowskiProve(puzzle, &negated, []*big.Int{puzzle.Z}, puzzle.T); err != nil {
+		t.Fatalf("error not empty for WesolowskiProve")
+	}
+	flipped.Pi.Sub(puzzle.N, flipped.Pi)
+	if err = WesolowskiVerifyErr(puzzle, &negated, flipped); !errors.Is(err, ErrMalformedProof) {
+		t.Errorf("sign-flipped Wesolowski proof is not rejected as malformed: %v", err)
+	}
+	if (PietrzakVDF{}).Verify(puzzle, y, proof) {
+		t.Errorf("pass verification with a proof of another VDF")
+	}
+	_, proof, err = SolvePuzzleWithProof(context.Background(), puzzle, &SolveOptions{VDF: PietrzakVDF{}})
+	if err != nil {
+		t.Fatalf("error not empty for SolvePuzzleWithProof")
+	}
+	pietrzak := proof.(*PietrzakProof)
+	for i := range pietrzak.Mu {
+		mu := pietrzak.Mu[i]
+		pietrzak.Mu[i] = new(big.Int).Mod(new(big.Int).Mul(mu, big2), puzzle.N)
+		if PietrzakVerify(puzzle, y, pietrzak) {
+			t.Errorf("pass verification with a tampered midpoint %d", i)
+		}
+		pietrzak.Mu[i] = mu
+	}
+	if PietrzakVerify(puzzle, y, &PietrzakProof{Mu: pietrzak.Mu[1:]}) {
+		t.Errorf("pass verification with a missing midpoint")
+	}
+	// -y has the same square as y, the proof for it passes in Z_N^* but -y is not an element of QR_N^+
+	forged, err := PietrzakProve(puzzle, &negated, []*big.Int{puzzle.Z}, puzzle.T)
+	if err != nil {
+		t.Fatalf("error not empty for PietrzakProve")
+	}
+	if err = PietrzakVerifyErr(puzzle, &negated, forged); !errors.Is(err, ErrMalformedProof) {
+		t.Errorf("proof for -y is not rejected as malformed: %v", err)
+	}
+	if forged, err = PietrzakProve(inZN, &negated, []*big.Int{puzzle.Z}, puzzle.T); err != nil || !PietrzakVerify(inZN, &negated, forged) {
+		t.Errorf("proof for -y does not pass in Z_N^*")
+	}
+
+	// the proof survives a restart from a checkpoint
+	puzzle, err = rsasetup.NewPuzzle(s, 5000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := SolveOptions{
+		Progress: func(done, total int64) {
+			if done == ctxCheckInterval {
+				cancel()
+			}
+		},
+		ProgressInterval: ctxCheckInterval,
+		CheckpointPath:   filepath.Join(t.TempDir(), "puzzle.checkpoint"),
+	}
+	if _, _, err = SolvePuzzleWithProof(ctx, puzzle, &opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SolvePuzzleWithProof is not cancelled")
+	}
+	y, proof, err = SolvePuzzleWithProof(context.Background(), puzzle, &opts)
+	if err != nil || !(WesolowskiVDF{}).Verify(puzzle, y, proof) {
+		t.Errorf("did not pass verification after resuming")
+	}
+	if _, err = SolvePuzzle(context.Background(), puzzle, &opts); !errors.Is(err, errCheckpointMismatch) {
+		t.Errorf("SolvePuzzle resumes a checkpoint kept for a proof")
+	}
+
+	// puzzles in a class group need no trusted setup
+	classGroup, err := ClassGroupFromSeed([]byte("VTLP puzzle test"), 256)
+	if err != nil {
+		t.Fatalf("error not empty for ClassGroupFromSeed")
+	}
+	group := classGroup.AsGroup()
+	z := group.Exp(group.Element(classGroup.Generator()), big.NewInt(123456789))
+	puzzle, err = NewGroupPuzzle(group, z, 300)
+	if err != nil {
+		t.Fatalf("error not empty for NewGroupPuzzle")
+	}
+	want := classGroup.exp(classGroup.Generator(), new(big.Int).Lsh(big.NewInt(123456789), 300))
+	for _, vdf := range []VDF{WesolowskiVDF{}, PietrzakVDF{}} {
+		y, proof, err := SolvePuzzleWithProof(context.Background(), puzzle, &SolveOptions{VDF: vdf})
+		if err != nil || !group.Equal(y, group.Element(want)) {
+			t.Fatalf("SolvePuzzleWithProof returns a wrong solution in the class group for %T", vdf)
+		}
+		if !vdf.Verify(puzzle, y, proof) {
+			t.Errorf("did not pass verification in the class group for %T", vdf)
+		}
+		if vdf.Verify(puzzle, z, proof) {
+			t.Errorf("pass verification with a wrong solution in the class group for %T", vdf)
+		}
+	}
+	if _, err = NewGroupPuzzle(group, big.NewInt(1), 300); err == nil {
+		t.Errorf("error empty for a puzzle input without a reduced form")
+	}
+}
+
+func TestTimeLockCiphertext(t *testing.T) {
+	rsasetup := RSAExpSetup()
+	plaintext := []byte("VTLP timed-release payload")
+	ad := []byte("header")
+	ciphertext, err := Seal(rsasetup, 3000, plaintext, ad)
+	if err != nil {
+		t.Fatalf("error not empty for Seal")
+	}
+	opened, err := Open(context.Background(), ciphertext, ad, nil)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open does not recover the plaintext")
+	}
+	opened, err = ciphertext.OpenWithSolution(rsasetup.Solution(ciphertext.Puzzle), ad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("OpenWithSolution does not recover the plaintext")
+	}
+	if _, err = Open(context.Background(), ciphertext, []byte("another header"), nil); err == nil {
+		t.Errorf("error empty for wrong additional data")
+	}
+
+	data, err := ciphertext.MarshalBinary()
+	if err != nil {
+		t.Fatalf("error not empty for MarshalBinary")
+	}
+	var decoded TimeLockCiphertext
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("error not empty for UnmarshalBinary")
+	}
+	opened, err = decoded.OpenWithSolution(rsasetup.Solution(decoded.Puzzle), ad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("decoded ciphertext does not open")
+	}
+	if err = decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Errorf("error empty for a truncated ciphertext")
+	}
+	// the keys of ciphertexts before signedPuzzleVersion are solutions in Z_N^*
+	for version := byte(1); version < signedPuzzleVersion; version++ {
+		data[0] = version
+		if err = decoded.UnmarshalBinary(data); err == nil {
+			t.Errorf("error empty for a ciphertext of version %d", version)
+		}
+	}
+
+	tampered := *ciphertext
+	tampered.Ciphertext = append([]byte{}, ciphertext.Ciphertext...)
+	tampered.Ciphertext[0] ^= 1
+	if _, err = tampered.OpenWithSolution(rsasetup.Solution(ciphertext.Puzzle), ad); err == nil {
+		t.Errorf("error empty for a tampered ciphertext")
+	}
+	tampered = *ciphertext
+	tampered.Puzzle = &Puzzle{N: ciphertext.Puzzle.N, T: ciphertext.Puzzle.T - 1, Z: ciphertext.Puzzle.Z}
+	if _, err = Open(context.Background(), &tampered, ad, nil); err == nil {
+		t.Errorf("error empty for a tampered puzzle")
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	opts := CalibrationOptions{Warmup: 10 * time.Millisecond, Samples: 5, SampleDuration: 20 * time.Millisecond}
+	calibration, err := Calibrate(context.Background(), 1024, &opts)
+	if err != nil {
+		t.Fatalf("error not empty for Calibrate")
+	}
+	if calibration.Bits != 1024 || calibration.Samples != 5 || calibration.Rate <= 0 || calibration.UpperRate() < calibration.Rate {
+		t.Errorf("wrong calibration %+v", calibration)
+	}
+	T, err := calibration.SquaringsFor(time.Second, SafetyModel{})
+	if err != nil || T < int64(calibration.Rate) {
+		t.Errorf("SquaringsFor returns %d for a rate of %f", T, calibration.Rate)
+	}
+	if calibration.Duration(T) < time.Second {
+		t.Errorf("puzzle takes less than the requested delay")
+	}
+	safer, err := calibration.SquaringsFor(time.Second, SafetyModel{HardwareSpeedup: 10, YearlySpeedup: 0.5})
+	if err != nil || safer < 10*T-10 {
+		t.Errorf("SquaringsFor ignores the safety model")
+	}
+	if factor := (SafetyModel{HardwareSpeedup: 2, YearlySpeedup: 1}).Factor(time.Duration(2 * hoursPerYear * float64(time.Hour))); math.Abs(factor-8) > 1e-9 {
+		t.Errorf("wrong safety factor %f", factor)
+	}
+	if _, err = calibration.SquaringsFor(0, SafetyModel{}); err == nil {
+		t.Errorf("error empty for a zero delay")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = Calibrate(ctx, 1024, &opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("Calibrate is not cancelled")
+	}
+}
+
+func TestTrustedSetupForQRN(t *testing.T) {
+	setup, p, q, err := TrustedSetupForQRN(context.Background(), crand.Reader, 256)
+	if err != nil {
+		t.Fatalf("error not empty for TrustedSetupForQRN")
+	}
+	if setup.N.BitLen() != 256 || new(big.Int).Mul(p, q).Cmp(setup.N) != 0 {
+		t.Errorf("wrong modulus")
+	}
+	for _, prime := range []*big.Int{p, q} {
+		if !prime.ProbablyPrime(20) || !new(big.Int).Rsh(prime, 1).ProbablyPrime(20) {
+			t.Errorf("%s is not a safe prime", prime.String())
+		}
+	}
+	if qr := NewSignedQRGroup(setup.N); !qr.IsElement(setup.G) || !qr.IsElement(setup.H) {
+		t.Errorf("generators are not in QR_N^+")
+	}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	var x, C big.Int
+	x.SetInt64(123456789)
+	C.Set(NewSignedQRGroup(pp.N).Exp(pp.G, &x))
+	proof, err := ZKPoKEProve(&pp, pp.G, &x, &C)
+	if err != nil || !ZKPoKEVerify(&pp, pp.G, &C, proof) {
+		t.Errorf("proofs do not work with the generated setup")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, _, err = TrustedSetupForQRN(ctx, crand.Reader, 256); !errors.Is(err, context.Canceled) {
+		t.Errorf("TrustedSetupForQRN is not cancelled")
+	}
+	if _, _, _, err = TrustedSetupForQRN(context.Background(), crand.Reader, 16); err == nil {
+		t.Errorf("error empty for a short modulus")
+	}
+
+	params, err := RandomSetupForUniversalHash(context.Background(), crand.Reader, 256)
+	if err != nil {
+		t.Fatalf("error not empty for RandomSetupForUniversalHash")
+	}
+	if params.P.BitLen() != 256 || !params.P.ProbablyPrime(20) || params.A.Cmp(params.P) >= 0 || params.B.Cmp(params.P) >= 0 {
+		t.Errorf("wrong universal hash parameters")
+	}
+}
+
+func TestGenerateSafePrime(t *testing.T) {
+	for _, bits := range []int{3, 10, minSieveBits, 128, 512} {
+		p, err := GenerateSafePrime(context.Background(), crand.Reader, bits)
+		if err != nil {
+			t.Fatalf("error not empty for GenerateSafePrime with %d bits", bits)
+		}
+		if p.BitLen() != bits || !p.ProbablyPrime(20) || !new(big.Int).Rsh(p, 1).ProbablyPrime(20) {
+			t.Errorf("%s is not a %d-bit safe prime", p.String(), bits)
+		}
+	}
+
+	// the sieve rejects exactly the candidates where a small prime divides p' or 2p'+1
+	primes := getSmallPrimes()[:50]
+	start := big.NewInt(1000001)
+	residues := make([]uint64, len(primes))
+	for i, prime := range primes {
+		residues[i] = new(big.Int).Mod(start, new(big.Int).SetUint64(prime)).Uint64()
+	}
+	var candidate, p, temp big.Int
+	for step := uint64(0); step < 2000; step += 2 {
+		candidate.Add(start, new(big.Int).SetUint64(step))
+		p.Lsh(&candidate, 1)
+		p.Add(&p, big1)
+		want := true
+		for _, prime := range primes {
+			r := new(big.Int).SetUint64(prime)
+			if temp.Mod(&candidate, r).Sign() == 0 || temp.Mod(&p, r).Sign() == 0 {
+				want = false
+			}
+		}
+		if sievePasses(residues, primes, step) != want {
+			t.Errorf("sieve is wrong for %s", candidate.String())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GenerateSafePrime(ctx, crand.Reader, 512); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateSafePrime is not cancelled")
+	}
+	if _, err := GenerateSafePrime(context.Background(), bytes.NewReader(nil), 512); err == nil {
+		t.Errorf("error empty for an empty source of randomness")
+	}
+
+	rsasetup, err := GenerateRSAExpSetup(context.Background(), crand.Reader, 256)
+	if err != nil {
+		t.Fatalf("error not empty for GenerateRSAExpSetup")
+	}
+	if rsasetup.RSAMod.BitLen() != 256 || !isQR(rsasetup.Base, rsasetup.P, rsasetup.Q) {
+		t.Errorf("wrong RSA setup")
+	}
+	s := NewSignedQRGroup(rsasetup.RSAMod).Exp(rsasetup.Base, big2)
+	puzzle, err := rsasetup.NewPuzzle(s, 100)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	if y, err := SolvePuzzle(context.Background(), puzzle, nil); err != nil || y.Cmp(s) != 0 {
+		t.Errorf("puzzle of a generated setup does not solve")
+	}
+}
+
+func TestDistributedSetup(t *testing.T) {
+	for _, parties := range []int{3, 5} {
+		N, members, err := distributedModulus(context.Background(), parties, 256, crand.Reader)
+		if err != nil {
+			t.Fatalf("error not empty for distributedModulus with %d parties", parties)
+		}
+		var p, q big.Int
+		for _, member := range members {
+			p.Add(&p, member.p)
+			q.Add(&q, member.q)
+		}
+		if N.BitLen() != 256 || new(big.Int).Mul(&p, &q).Cmp(N) != 0 {
+			t.Errorf("N is not the product of the shared factors")
+		}
+		if !p.ProbablyPrime(20) || !q.ProbablyPrime(20) || p.Bit(0) != 1 || p.Bit(1) != 1 || q.Bit(0) != 1 || q.Bit(1) != 1 {
+			t.Errorf("shared factors are not primes that are 3 mod 4")
+		}
+	}
+
+	setup, err := DistributedSetup(context.Background(), 3, 256, crand.Reader)
+	if err != nil {
+		t.Fatalf("error not empty for DistributedSetup")
+	}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	var x, C big.Int
+	x.SetInt64(123456789)
+	C.Set(NewSignedQRGroup(pp.N).Exp(pp.G, &x))
+	proof, err := ZKPoKEProve(&pp, pp.G, &x, &C)
+	if err != nil || !ZKPoKEVerify(&pp, pp.G, &C, proof) {
+		t.Errorf("proofs do not work with the distributed setup")
+	}
+
+	// the biprimality test rejects p = 13*(2^61-1) and the prime q = 2^89-1, both are 3 mod 4
+	var composite, prime big.Int
+	composite.SetString("29975959119778021363", 10)
+	prime.SetString("618970019642690137449562111", 10)
+	if composite.ProbablyPrime(20) || composite.Bit(1) != 1 || composite.Bit(0) != 1 {
+		t.Fatalf("wrong test composite")
+	}
+	N := new(big.Int).Mul(&composite, &prime)
+	transport := NewMemoryTransport(3)
+	results := make(chan bool, 3)
+	for i := 0; i < 3; i++ {
+		member := newParty(i, 3, N.BitLen(), N, transport, crand.Reader)
+		member.p, member.q = new(big.Int), new(big.Int)
+		if i == 0 {
+			member.p.Set(&composite)
+			member.q.Set(&prime)
+		}
+		go func() {
+			ok, err := member.biprimality(context.Background(), N)
+			results <- ok && err == nil
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		if <-results {
+			t.Errorf("biprimality test passes a composite factor")
+		}
+	}
+
+	if _, err = DistributedSetup(context.Background(), 2, 256, crand.Reader); err == nil {
+		t.Errorf("error empty for 2 parties")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = DistributedSetup(ctx, 3, 256, crand.Reader); !errors.Is(err, context.Canceled) {
+		t.Errorf("DistributedSetup is not cancelled")
+	}
+}
+
+func TestClassGroup(t *testing.T) {
+	// D = -23 has class number 3
+	small, err := NewClassGroup(big.NewInt(-23))
+	if err != nil {
+		t.Fatalf("error not empty for NewClassGroup")
+	}
+	g := small.Generator()
+	if g.Equal(small.Identity()) || !small.exp(g, big.NewInt(3)).Equal(small.Identity()) {
+		t.Errorf("wrong order of the generator of D = -23")
+	}
+	if _, err = NewClassGroup(big.NewInt(-27)); err == nil {
+		t.Errorf("error empty for D = 5 mod 8")
+	}
+
+	group, err := ClassGroupFromSeed([]byte("VTLP test"), 512)
+	if err != nil {
+		t.Fatalf("error not empty for ClassGroupFromSeed")
+	}
+	if group.D.BitLen() != 512 || !new(big.Int).Neg(group.D).ProbablyPrime(20) {
+		t.Errorf("wrong discriminant")
+	}
+	again, _ := ClassGroupFromSeed([]byte("VTLP test"), 512)
+	if again.D.Cmp(group.D) != 0 {
+		t.Errorf("discriminant is not deterministic")
+	}
+	g = group.Generator()
+	identity := group.Identity()
+	if !group.IsValid(g) || !group.IsValid(identity) {
+		t.Fatalf("generator or identity is invalid")
+	}
+	forms := make([]*Form, 8)
+	for i := range forms {
+		x, _ := crand.Int(crand.Reader, Min1024)
+		forms[i] = group.exp(g, x)
+		if !group.IsValid(forms[i]) {
+			t.Fatalf("Exp returns an invalid form")
+		}
+	}
+	for i, f := range forms {
+		h := forms[(i+1)%len(forms)]
+		k := forms[(i+2)%len(forms)]
+		if !group.square(f).Equal(group.compose(f, f)) {
+			t.Errorf("NUDUPL does not match composition for %s", f.String())
+		}
+		if !group.compose(f, h).Equal(group.compose(h, f)) {
+			t.Errorf("composition is not commutative")
+		}
+		if !group.compose(group.compose(f, h), k).Equal(group.compose(f, group.compose(h, k))) {
+			t.Errorf("composition is not associative")
+		}
+		if !group.compose(f, identity).Equal(f) || !group.compose(f, group.Inverse(f)).Equal(identity) {
+			t.Errorf("wrong identity or inverse")
+		}
+	}
+	a, b := big.NewInt(123456789), big.NewInt(987654321)
+	sum := new(big.Int).Add(a, b)
+	if !group.exp(g, sum).Equal(group.compose(group.exp(g, a), group.exp(g, b))) {
+		t.Errorf("Exp is not a homomorphism")
+	}
+	// a negative exponent raises the inverse class, so g^-a * g^(a+b) = g^b
+	power, err := group.Exp(g, new(big.Int).Neg(a))
+	if err != nil || !power.Equal(group.Inverse(group.exp(g, a))) {
+		t.Errorf("wrong Exp with a negative exponent")
+	}
+	if composed, err := group.Compose(power, group.exp(g, sum)); err != nil || !composed.Equal(group.exp(g, b)) {
+		t.Errorf("Exp with a negative exponent is not a homomorphism")
+	}
+	formGroup := group.AsGroup()
+	x, y := formGroup.Element(g), formGroup.Element(group.exp(g, b))
+	want := formGroup.Mul(formGroup.Exp(x, new(big.Int).Neg(a)), formGroup.Exp(y, sum))
+	if !formGroup.Equal(formGroup.Exp(x, new(big.Int).Neg(a)), formGroup.Element(power)) ||
+		!formGroup.Equal(formGroup.MultiExp([]*big.Int{x, y}, []*big.Int{new(big.Int).Neg(a), sum}), want) {
+		t.Errorf("wrong FormGroup exponentiation with a negative exponent")
+	}
+
+	// forms of another discriminant, also multiples of a valid form, and non-positive forms are rejected instead of composed
+	other := small.Generator()
+	invalid := []*Form{
+		nil,
+		other,
+		{A: big.NewInt(0), B: g.B, C: g.C},
+		{A: new(big.Int).Lsh(g.A, 1), B: new(big.Int).Lsh(g.B, 1), C: new(big.Int).Lsh(g.C, 1)},
+	}
+	for _, f := range invalid {
+		if _, err := group.Compose(g, f); err == nil {
+			t.Errorf("error empty for Compose with the form %v", f)
+		}
+		if _, err := group.Compose(f, g); err == nil {
+			t.Errorf("error empty for Compose with the form %v", f)
+		}
+		if _, err := group.Square(f); err == nil {
+			t.Errorf("error empty for Square of the form %v", f)
+		}
+		if _, err := group.Exp(f, a); err == nil {
+			t.Errorf("error empty for Exp of the form %v", f)
+		}
+	}
+	if composed, err := group.Compose(g, identity); err != nil || !composed.Equal(g) {
+		t.Errorf("Compose rejects valid forms")
+	}
+	if squared, err := group.Square(g); err != nil || !squared.Equal(group.compose(g, g)) {
+		t.Errorf("Square rejects a valid form")
+	}
+	f, err := group.NewForm(g.A, g.B)
+	if err != nil || !f.Equal(g) {
+		t.Errorf("NewForm does not recover the generator")
+	}
+	if _, err = group.NewForm(big.NewInt(2), big.NewInt(2)); err == nil {
+		t.Errorf("error empty for a form of another discriminant")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	setup := TrustedSetup()
+	rsa := NewRSAGroup(setup.N)
+	data := rsa.Encode(setup.G)
+	if x, err := rsa.Decode(data); err != nil || !rsa.Equal(x, setup.G) {
+		t.Errorf("RSAGroup encoding does not round trip")
+	}
+	if _, err := rsa.Decode(setup.N.Bytes()); err == nil {
+		t.Errorf("RSAGroup decodes N")
+	}
+	if _, err := rsa.Decode(data[1:]); err == nil {
+		t.Errorf("RSAGroup decodes a short encoding")
+	}
+
+	classGroup, err := ClassGroupFromSeed([]byte("VTLP group test"), 256)
+	if err != nil {
+		t.Fatalf("error not empty for ClassGroupFromSeed")
+	}
+	group := classGroup.AsGroup()
+	g := group.Element(classGroup.Generator())
+	f, err := group.Form(g)
+	if err != nil || !f.Equal(classGroup.Generator()) {
+		t.Errorf("FormGroup element does not carry the generator")
+	}
+	if x, err := group.Decode(group.Encode(g)); err != nil || !group.Equal(x, g) {
+		t.Errorf("FormGroup encoding does not round trip")
+	}
+	if _, err = group.Decode(group.Encode(new(big.Int).Add(g, big1))); err == nil {
+		t.Errorf("FormGroup decodes an integer without a reduced form")
+	}
+	if !group.Equal(group.Mul(g, group.Identity()), g) {
+		t.Errorf("wrong FormGroup identity")
+	}
+
+	r, _ := crand.Int(crand.Reader, group.Bound())
+	pp := NewGroupParameters(group, g, group.Exp(g, r))
+	var x, C1, C2, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(group.Exp(g, &x))
+	C2.Set(group.Exp(g, &x2e))
+
+	pokeStar, err := PoKEStarProve(pp, &C1, &x)
+	if err != nil || !PoKEStarVerify(pp, &C1, pokeStar) {
+		t.Errorf("PoKEStar fails in the class group")
+	}
+	poe, err := PoEProveInGroup(group, g, &C1, &x)
+	if err != nil || !PoEVerifyInGroup(group, g, &C1, &x, poe) {
+		t.Errorf("PoE fails in the class group")
+	}
+	proof, err := ZKPoMoDEFastProve(pp, &C1, &C2, &n, &e, &xmod, &x)
+	if err != nil || !ZKPoMoDEFastVerify(pp, &C1, &C2, &n, &e, &xmod, proof) {
+		t.Errorf("ZKPoMoDEFast fails in the class group")
+	}
+	if ZKPoMoDEFastVerify(pp, &C2, &C1, &n, &e, &xmod, proof) {
+		t.Errorf("ZKPoMoDEFast passes verification with swapped commitments")
+	}
+	invalid := PoKEStarProof{Q: big.NewInt(1), R: pokeStar.R}
+	if PoKEStarVerify(pp, &C1, &invalid) || PoKEStarVerify(pp, big.NewInt(1), &invalid) {
+		t.Errorf("PoKEStar passes verification with an element without a reduced form")
+	}
+}
+
+func TestSignedQRGroup(t *testing.T) {
+	setup := TrustedSetup()
+	group := NewSignedQRGroup(setup.N)
+	minusOne := new(big.Int).Sub(setup.N, big1)
+	g := group.Element(setup.G)
+	if !group.IsElement(g) || !group.IsElement(group.Identity()) {
+		t.Fatalf("generator or identity is not in QR_N^+")
+	}
+	if group.IsElement(minusOne) || group.IsElement(big.NewInt(0)) || group.IsElement(setup.N) {
+		t.Errorf("QR_N^+ contains -1, 0 or N")
+	}
+	if !group.Equal(group.Element(minusOne), group.Identity()) {
+		t.Errorf("-1 is not identified with 1")
+	}
+	if !group.Equal(group.Element(new(big.Int).Sub(setup.N, g)), g) {
+		t.Errorf("-g is not identified with g")
+	}
+	if x, err := group.Decode(group.Encode(g)); err != nil || !group.Equal(x, g) {
+		t.Errorf("SignedQRGroup encoding does not round trip")
+	}
+	if _, err := group.Decode(group.Encode(new(big.Int).Sub(setup.N, g))); err == nil {
+		t.Errorf("SignedQRGroup decodes a non-canonical element")
+	}
+
+	pp := NewGroupParameters(group, g, group.Element(setup.H))
+	var x, C1, C2, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(group.Exp(g, &x))
+	C2.Set(group.Exp(g, &x2e))
+	proof, err := PoKEStarProve(pp, &C1, &x)
+	if err != nil || !PoKEStarVerify(pp, &C1, proof) {
+		t.Errorf("PoKEStar fails in QR_N^+")
+	}
+	negated := PoKEStarProof{Q: new(big.Int).Sub(setup.N, proof.Q), R: proof.R}
+	if PoKEStarVerify(pp, &C1, &negated) {
+		t.Errorf("PoKEStar passes verification with -Q")
+	}
+	if PoKEStarVerify(pp, new(big.Int).Sub(setup.N, &C1), proof) {
+		t.Errorf("PoKEStar passes verification with the statement -C")
+	}
+	fastProof, err := ZKPoMoDEFastProve(pp, &C1, &C2, &n, &e, &xmod, &x)
+	if err != nil || !ZKPoMoDEFastVerify(pp, &C1, &C2, &n, &e, &xmod, fastProof) {
+		t.Errorf("ZKPoMoDEFast fails in QR_N^+")
+	}
+	fastProof.pi2.D = new(big.Int).Sub(setup.N, fastProof.pi2.D)
+	if ZKPoMoDEFastVerify(pp, &C1, &C2, &n, &e, &xmod, fastProof) {
+		t.Errorf("ZKPoMoDEFast passes verification with a negated proof element")
+	}
+}
+
+func TestMontgomery(t *testing.T) {
+	for _, bits := range []int{64, 65, 521, 1024, 2048, 3072, 4096} {
+		m, _ := crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(bits)))
+		m.SetBit(m, bits-1, 1)
+		m.SetBit(m, 0, 1)
+		mont, err := newMontgomery(m)
+		if err != nil {
+			t.Fatalf("error not empty for newMontgomery")
+		}
+		for i := 0; i < 20; i++ {
+			x, _ := crand.Int(crand.Reader, m)
+			y, _ := crand.Int(crand.Reader, m)
+			if i == 0 {
+				x.Sub(m, big1)
+				y.Sub(m, big1)
+			}
+			xm, ym := mont.toMontgomery(x), mont.toMontgomery(y)
+			z := make([]uint64, mont.width())
+			mont.mul(z, xm, ym)
+			want := new(big.Int).Mul(x, y)
+			if mont.fromMontgomery(z).Cmp(want.Mod(want, m)) != 0 {
+				t.Fatalf("wrong Montgomery product for %d bits", bits)
+			}
+			mont.square(xm, xm)
+			want.Mul(x, x)
+			if mont.fromMontgomery(xm).Cmp(want.Mod(want, m)) != 0 {
+				t.Fatalf("wrong Montgomery square for %d bits", bits)
+			}
+		}
+	}
+	if _, err := newMontgomery(big.NewInt(1024)); err == nil {
+		t.Errorf("newMontgomery accepts an even modulus")
+	}
+}
+
+func TestSquarer(t *testing.T) {
+	for _, bits := range []int{64, 1024, 2048, 3072, 4096} {
+		for _, odd := range []bool{true, false} {
+			N, _ := crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(bits)))
+			N.SetBit(N, bits-1, 1)
+			N.SetBit(N, 0, 0)
+			if odd {
+				N.SetBit(N, 0, 1)
+			}
+			x, _ := crand.Int(crand.Reader, N)
+			squarer := NewSquarer(N, x)
+			want := new(big.Int).Set(x)
+			for i := 0; i < 100; i++ {
+				squarer.Square()
+				want.Mul(want, want)
+				want.Mod(want, N)
+				if squarer.Int().Cmp(want) != 0 {
+					t.Fatalf("wrong square for %d bits after %d squarings", bits, i+1)
+				}
+			}
+			squarer.SquareN(1000)
+			want.Exp(x, new(big.Int).Lsh(big1, 1100), N)
+			if squarer.Int().Cmp(want) != 0 {
+				t.Fatalf("wrong result of SquareN for %d bits", bits)
+			}
+			if allocs := testing.AllocsPerRun(100, squarer.Square); allocs != 0 {
+				t.Errorf("Square allocates %v times for %d bits", allocs, bits)
+			}
+			squarer.Set(new(big.Int).Add(N, big2))
+			squarer.Square()
+			if squarer.Int().Cmp(big.NewInt(4)) != 0 {
+				t.Errorf("Set does not reduce modulo N for %d bits", bits)
+			}
+		}
+	}
+}
+
+func TestMultiExp(t *testing.T) {
+	setup := TrustedSetup()
+	classGroup, err := ClassGroupFromSeed([]byte("VTLP multi-exponentiation test"), 256)
+	if err != nil {
+		t.Fatalf("error not empty for ClassGroupFromSeed")
+	}
+	formGroup := classGroup.AsGroup()
+	qr := NewSignedQRGroup(setup.N)
+	groups := []struct {
+		name  string
+		group Group
+		g     *big.Int
+	}{
+		{"RSA", NewRSAGroup(setup.N), setup.G},
+		{"QR+", qr, qr.Element(setup.G)},
+		{"RSA with an even modulus", NewRSAGroup(new(big.Int).Lsh(setup.N, 1)), setup.G},
+		{"class group", formGroup, formGroup.Element(classGroup.Generator())},
+	}
+	for _, tc := range groups {
+		// the counts cover one base, Straus and Pippenger, the exponent lengths cover 0, 1 and long exponents
+		for _, count := range []int{1, 2, 3, 40} {
+			bases := make([]*big.Int, count)
+			exps := make([]*big.Int, count)
+			for i := range bases {
+				r, _ := crand.Int(crand.Reader, big.NewInt(1<<20))
+				bases[i] = tc.group.Exp(tc.g, r)
+				exps[i], _ = crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(1+i*97%300)))
+			}
+			if count == 3 {
+				exps[1].SetInt64(0)
+				exps[2].SetInt64(1)
+			}
+			want := multiExpSeparately(tc.group, bases, exps)
+			if got := tc.group.MultiExp(bases, exps); !tc.group.Equal(got, want) {
+				t.Errorf("wrong %s multi-exponentiation of %d bases", tc.name, count)
+			}
+		}
+		zero := []*big.Int{new(big.Int), new(big.Int)}
+		if got := tc.group.MultiExp([]*big.Int{tc.g, tc.g}, zero); !tc.group.Equal(got, tc.group.Identity()) {
+			t.Errorf("%s multi-exponentiation with zero exponents is not the identity", tc.name)
+		}
+	}
+
+	// the RSA group leaves few bases to big.Int.Exp, so Straus and Pippenger are also checked directly
+	rsa := NewRSAGroup(setup.N)
+	mont, err := newMontgomery(setup.N)
+	if err != nil {
+		t.Fatalf("error not empty for newMontgomery")
+	}
+	for _, count := range []int{2, 3, 40} {
+		bases := make([]*big.Int, count)
+		elements := make([][]uint64, count)
+		exps := make([]*big.Int, count)
+		for i := range bases {
+			bases[i], _ = crand.Int(crand.Reader, setup.N)
+			elements[i] = mont.toMontgomery(bases[i])
+			exps[i], _ = crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(1+i*97%300)))
+		}
+		want := multiExpSeparately(rsa, bases, exps)
+		if got := straus[[]uint64](montgomeryMultiplier{mont: mont}, elements, exps); mont.fromMontgomery(got).Cmp(want) != 0 {
+			t.Errorf("wrong Straus multi-exponentiation of %d bases", count)
+		}
+		for c := 1; c <= 6; c++ {
+			if got := pippenger[[]uint64](montgomeryMultiplier{mont: mont}, elements, exps, c); mont.fromMontgomery(got).Cmp(want) != 0 {
+				t.Errorf("wrong Pippenger multi-exponentiation of %d bases with %d bit windows", count, c)
+			}
+		}
+	}
+	if formGroup.MultiExp([]*big.Int{big1, formGroup.Identity()}, []*big.Int{big2, big2}).Sign() != 0 {
+		t.Errorf("FormGroup multi-exponentiates an integer without a reduced form")
+	}
+	x, r := big.NewInt(1234567), big.NewInt(7654321)
+	want := new(big.Int).Exp(setup.G, x, setup.N)
+	want.Mul(want, new(big.Int).Exp(setup.H, r, setup.N))
+	if MultiExp(setup.G, x, setup.H, r, setup.N).Cmp(want.Mod(want, setup.N)) != 0 {
+		t.Errorf("wrong MultiExp")
+	}
+}
+
+func TestFixedBase(t *testing.T) {
+	setup := TrustedSetup()
+	classGroup, err := ClassGroupFromSeed([]byte("VTLP fixed-base test"), 256)
+	if err != nil {
+		t.Fatalf("error not empty for ClassGroupFromSeed")
+	}
+	formGroup := classGroup.AsGroup()
+	qr := NewSignedQRGroup(setup.N)
+	groups := []struct {
+		name  string
+		group Group
+		g     *big.Int
+	}{
+		{"RSA", NewRSAGroup(setup.N), setup.G},
+		{"QR+", qr, qr.Element(setup.G)},
+		{"class group", formGroup, formGroup.Element(classGroup.Generator())},
+	}
+	for _, tc := range groups {
+		table := NewFixedBase(tc.group, tc.g, 600)
+		if table.Bits() < 600 {
+			t.Fatalf("%s table covers %d bits", tc.name, table.Bits())
+		}
+		for _, bits := range []int{0, 1, 2, 300, 600, table.Bits() + 1, 1000} {
+			e, _ := crand.Int(crand.Reader, new(big.Int).Lsh(big1, uint(bits)))
+			e.SetBit(e, bits, 1)
+			if bits == 0 {
+				e.SetInt64(0)
+			}
+			if !tc.group.Equal(table.Exp(tc.group, e), tc.group.Exp(tc.g, e)) {
+				t.Errorf("wrong %s fixed-base exponentiation with a %d bit exponent", tc.name, e.BitLen())
+			}
+		}
+	}
+
+	var x, C1, C2, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	C2.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x2e))
+	plain := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	pp.Precompute(0)
+	// the tables are built by whichever prover needs them first
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			proof, err := ZKPoMoDEFastProve(&pp, &C1, &C2, &n, &e, &xmod, &x)
+			if err == nil {
+				err = ZKPoMoDEFastVerifyErr(&plain, &C1, &C2, &n, &e, &xmod, proof)
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("ZKPoMoDEFast proof with precomputed tables fails: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "tables")
+	if err := plain.StorePrecomputed(path); err == nil {
+		t.Errorf("StorePrecomputed stores parameters without tables")
+	}
+	if err := pp.StorePrecomputed(path); err != nil {
+		t.Fatalf("error not empty for StorePrecomputed: %v", err)
+	}
+	loaded := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	if err := loaded.LoadPrecomputed(path); err != nil {
+		t.Fatalf("error not empty for LoadPrecomputed: %v", err)
+	}
+	tableG, tableH := loaded.fixed.get(&loaded)
+	if tableG.Bits() != pp.fixed.g.Bits() || tableH.Powers[len(tableH.Powers)-1].Cmp(pp.fixed.h.Powers[len(tableH.Powers)-1]) != 0 {
+		t.Errorf("loaded tables differ from the stored tables")
+	}
+	proof, err := PoKEStarProve(&loaded, &C1, &x)
+	if err != nil || !PoKEStarVerify(&plain, &C1, proof) {
+		t.Errorf("PoKEStar proof with loaded tables fails")
+	}
+
+	other := PublicParameters{N: setup.N, G: setup.H, H: setup.G}
+	if err := other.LoadPrecomputed(path); !errors.Is(err, errPrecomputationMismatch) {
+		t.Errorf("tables of other generators are loaded: %v", err)
+	}
+	inZN := NewGroupParameters(NewRSAGroup(setup.N), setup.G, setup.H)
+	if err := inZN.LoadPrecomputed(path); !errors.Is(err, errPrecomputationMismatch) {
+		t.Errorf("tables of another group are loaded: %v", err)
+	}
+
+	// a wrong power changes the exponentiations of verifiers, every entry is checked on loading
+	tampered := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	tampered.Precompute(0)
+	_, tableH = tampered.fixed.get(&tampered)
+	last := len(tableH.Powers) - 1
+	tableH.Powers[last] = qr.Mul(tableH.Powers[last], setup.G)
+	tamperedPath := filepath.Join(t.TempDir(), "tampered")
+	if err := tampered.StorePrecomputed(tamperedPath); err != nil {
+		t.Fatalf("error not empty for StorePrecomputed: %v", err)
+	}
+	if err := loaded.LoadPrecomputed(tamperedPath); !errors.Is(err, errInvalidFixedBase) {
+		t.Errorf("tables with a wrong power are loaded: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error not empty for ReadFile")
+	}
+	if err = os.WriteFile(path, data[:len(data)-1], 0600); err != nil {
+		t.Fatalf("error not empty for WriteFile")
+	}
+	truncated := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	if err := truncated.LoadPrecomputed(path); err == nil {
+		t.Errorf("truncated tables are loaded")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	setup := TrustedSetup()
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	var x, C1, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	proof, err := ZKPoMoDEProve(&pp, &C1, &n, &e, &xmod, &x)
+	if err != nil {
+		t.Fatalf("error not empty for ZKPoMoDEProve")
+	}
+	if err = proof.Validate(&pp, &C1, &n, &e, &xmod); err != nil {
+		t.Errorf("valid proof fails validation: %v", err)
+	}
+	if err = proof.pi1.Validate(&pp, proof.D); err != nil {
+		t.Errorf("valid PoKEStar proof fails validation: %v", err)
+	}
+
+	rsasetup := RSAExpSetup()
+	puzzle, err := rsasetup.NewPuzzle(GenVRFSolution([]byte("VTLP test message"), rsasetup), 1000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	y, pietrzak, err := SolvePuzzleWithProof(context.Background(), puzzle, &SolveOptions{VDF: PietrzakVDF{}})
+	if err != nil {
+		t.Fatalf("error not empty for SolvePuzzleWithProof")
+	}
+
+	pokeStar := *proof.pi1
+	pokeStar.Q = new(big.Int).Set(setup.N)
+	zkpoke := *proof.pi2.pi3
+	zkpoke.rx = new(big.Int).Neg(zkpoke.rx)
+	longRemainder := *proof.pi2.pi3
+	longRemainder.rrho = new(big.Int).Lsh(big1, maxChallengeBits)
+	zkpokde := *proof.pi2
+	zkpokde.pi2 = nil
+	shortPietrzak := PietrzakProof{Mu: pietrzak.(*PietrzakProof).Mu[1:]}
+	testCases := []struct {
+		name     string
+		err      error
+		proof    string
+		element  string
+		verified bool
+	}{
+		{"nil PoKEStar", (*PoKEStarProof)(nil).Validate(&pp, &C1), "PoKEStar", "proof",
+			PoKEStarVerify(&pp, &C1, nil)},
+		{"nil pp", proof.pi1.Validate(nil, &C1), "PoKEStar", "pp", PoKEStarVerify(nil, &C1, proof.pi1)},
+		{"Q = N", pokeStar.Validate(&pp, proof.D), "PoKEStar", "Q", PoKEStarVerify(&pp, proof.D, &pokeStar)},
+		{"zero statement", proof.pi1.Validate(&pp, big.NewInt(0)), "PoKEStar", "C",
+			PoKEStarVerify(&pp, big.NewInt(0), proof.pi1)},
+		{"negative rx", zkpoke.Validate(&pp, &C1, &C1), "ZKPoKE", "rx", ZKPoKEVerify(&pp, &C1, &C1, &zkpoke)},
+		{"long rrho", longRemainder.Validate(&pp, &C1, &C1), "ZKPoKE", "rrho",
+			ZKPoKEVerify(&pp, &C1, &C1, &longRemainder)},
+		{"nil PoKDE", (*PoKDEProof)(nil).Validate(&pp, &C1, &C1, &e), "PoKDE", "proof",
+			PoKDEVerify(&pp, &C1, &C1, &e, nil)},
+		{"missing PoE", proof.pi2.pi2.Validate(NewRSAGroup(setup.N), &C1, nil, &e), "PoE", "C",
+			PoEVerify(&C1, setup.N, nil, &e, proof.pi2.pi2)},
+		{"missing sub-proof", zkpokde.Validate(&pp, &C1, &C1, &e), "ZKPoKDE", "pi2",
+			ZKPoKDEVerify(&pp, &C1, &C1, &e, &zkpokde)},
+		{"xmod = n", proof.pi3.Validate(&pp, &C1, &n, &n), "ZKPoKEMod", "xmod",
+			ZKPoKEModVerify(&pp, &C1, &n, &n, proof.pi3)},
+		{"zero exponent", proof.Validate(&pp, &C1, &n, big.NewInt(0), &xmod), "ZKPoMoDE", "e",
+			ZKPoMoDEVerify(&pp, &C1, &n, big.NewInt(0), &xmod, proof)},
+		{"short Pietrzak", shortPietrzak.Validate(puzzle, y), "Pietrzak", "Mu",
+			PietrzakVerify(puzzle, y, &shortPietrzak)},
+		{"Pi shares a factor", (&WesolowskiProof{Pi: rsasetup.P}).Validate(puzzle, y), "Wesolowski", "Pi",
+			WesolowskiVerify(puzzle, y, &WesolowskiProof{Pi: rsasetup.P})},
+	}
+	for _, tc := range testCases {
+		var validationErr *ValidationError
+		if !errors.As(tc.err, &validationErr) {
+			t.Errorf("%s: no ValidationError", tc.name)
+			continue
+		}
+		if validationErr.Proof != tc.proof || validationErr.Element != tc.element {
+			t.Errorf("%s: wrong error %v", tc.name, tc.err)
+		}
+		if tc.verified {
+			t.Errorf("%s: pass verification", tc.name)
+		}
+	}
+}
+
+func TestVerifyErr(t *testing.T) {
+	setup := TrustedSetup()
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	var x, C1, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	proof, err := ZKPoMoDEProve(&pp, &C1, &n, &e, &xmod, &x)
+	if err != nil {
+		t.Fatalf("error not empty for ZKPoMoDEProve")
+	}
+	if err = ZKPoMoDEVerifyErr(&pp, &C1, &n, &e, &xmod, proof); err != nil {
+		t.Errorf("valid proof fails verification: %v", err)
+	}
+
+	err = PoKEStarVerifyErr(&pp, proof.D, nil)
+	if !errors.Is(err, ErrMalformedProof) || errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("nil proof is not reported as malformed: %v", err)
+	}
+	// the sub-proofs of ZKPoMoDE are bound to it, a wrong statement is checked on a proof of its own
+	pokeStar, err := PoKEStarProve(&pp, &C1, &x)
+	if err != nil {
+		t.Fatalf("error not empty for PoKEStarProve")
+	}
+	err = PoKEStarVerifyErr(&pp, setup.G, pokeStar)
+	if !errors.Is(err, ErrChallengeMismatch) || errors.Is(err, ErrMalformedProof) {
+		t.Errorf("wrong statement is not reported as a challenge mismatch: %v", err)
+	}
+
+	// tamper with pi3 inside pi2 after its challenges, so that only the final check fails
+	zkpoke := *proof.pi2.pi3
+	zkpoke.Qg = pp.group().Mul(zkpoke.Qg, setup.G)
+	zkpokde := *proof.pi2
+	zkpokde.pi3 = &zkpoke
+	tampered := *proof
+	tampered.pi2 = &zkpokde
+	err = ZKPoMoDEVerifyErr(&pp, &C1, &n, &e, &xmod, &tampered)
+	var subproofErr *SubproofError
+	if !errors.Is(err, ErrSubproofFailed) || !errors.As(err, &subproofErr) {
+		t.Fatalf("tampered sub-proof is not reported as a failed sub-proof: %v", err)
+	}
+	if subproofErr.Proof != "ZKPoMoDE" || subproofErr.Subproof != "pi2" {
+		t.Errorf("wrong sub-proof %s.%s", subproofErr.Proof, subproofErr.Subproof)
+	}
+	if !errors.As(subproofErr.Err, &subproofErr) || subproofErr.Proof != "ZKPoKDE" || subproofErr.Subproof != "pi3" {
+		t.Errorf("wrong nested sub-proof in %v", err)
+	}
+	if !errors.Is(err, ErrChallengeMismatch) || errors.Is(err, ErrMalformedProof) {
+		t.Errorf("the failure of the nested sub-proof is lost: %v", err)
+	}
+
+	rsasetup := RSAExpSetup()
+	puzzle, err := rsasetup.NewPuzzle(GenVRFSolution([]byte("VTLP test message"), rsasetup), 1000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	y, wesolowski, err := SolvePuzzleWithProof(context.Background(), puzzle, nil)
+	if err != nil {
+		t.Fatalf("error not empty for SolvePuzzleWithProof")
+	}
+	if err = WesolowskiVerifyErr(puzzle, y, wesolowski.(*WesolowskiProof)); err != nil {
+		t.Errorf("valid Wesolowski proof fails verification: %v", err)
+	}
+	if err = WesolowskiVerifyErr(puzzle, puzzle.Z, wesolowski.(*WesolowskiProof)); !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("wrong solution is not reported as a challenge mismatch: %v", err)
+	}
+}
+
+// genBatch returns count PoKE* and PoE proofs for g^x_i = C_i in the group of pp
+func genBatch(t testing.TB, pp *PublicParameters, count int) ([]*big.Int, []*PoKEStarProof, []PoEStatement, []*PoEProof) {
+	group := pp.group()
+	C := make([]*big.Int, count)
+	pokeStar := make([]*PoKEStarProof, count)
+	statements := make([]PoEStatement, count)
+	poe := make([]*PoEProof, count)
+	for i := 0; i < count; i++ {
+		x, _ := crand.Int(crand.Reader, Min1024)
+		C[i] = group.Exp(pp.G, x)
+		var err error
+		pokeStar[i], err = PoKEStarProve(pp, C[i], x)
+		if err != nil {
+			t.Fatalf("error not empty for PoKEStarProve")
+		}
+		statements[i] = PoEStatement{Base: pp.G, C: C[i], X: x}
+		poe[i], err = PoEProveInGroup(group, pp.G, C[i], x)
+		if err != nil {
+			t.Fatalf("error not empty for PoEProveInGroup")
+		}
+	}
+	return C, pokeStar, statements, poe
+}
+
+func TestBatchVerify(t *testing.T) {
+	setup := TrustedSetup()
+	group := NewSignedQRGroup(setup.N)
+	pp := NewGroupParameters(group, group.Element(setup.G), group.Element(setup.H))
+	C, pokeStar, statements, poe := genBatch(t, pp, 8)
+	if err := BatchPoKEStarVerify(pp, C, pokeStar); err != nil {
+		t.Errorf("valid PoKE* batch fails verification: %v", err)
+	}
+	if err := BatchPoEVerify(group, statements, poe); err != nil {
+		t.Errorf("valid PoE batch fails verification: %v", err)
+	}
+
+	pokeStar[5] = &PoKEStarProof{Q: group.Mul(pokeStar[5].Q, pp.G), R: pokeStar[5].R}
+	pokeStar[2] = nil
+	err := BatchPoKEStarVerify(pp, C, pokeStar)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 2 || batchErr.Failed[0] != 2 || batchErr.Failed[1] != 5 {
+		t.Fatalf("PoKE* batch does not identify the invalid proofs: %v", err)
+	}
+	if !errors.Is(batchErr.Errs[0], ErrMalformedProof) || !errors.Is(batchErr.Errs[1], ErrChallengeMismatch) {
+		t.Errorf("wrong errors of the invalid PoKE* proofs: %v", batchErr.Errs)
+	}
+
+	statements[3].X = new(big.Int).Add(statements[3].X, big1)
+	err = BatchPoEVerify(group, statements, poe)
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0] != 3 {
+		t.Errorf("PoE batch does not identify the invalid proof: %v", err)
+	}
+	if BatchPoEVerify(group, statements[1:], poe) == nil {
+		t.Errorf("PoE batch accepts different numbers of statements and proofs")
+	}
+
+	// a sign-flipped proof passes half of the combinations in Z_N^*, the batch must agree with the single verifier
+	inZN := NewRSAGroup(setup.N)
+	pp = NewGroupParameters(inZN, setup.G, setup.H)
+	C, pokeStar, statements, poe = genBatch(t, pp, 2)
+	pokeStar[1].Q.Sub(setup.N, pokeStar[1].Q)
+	poe[1].Q.Sub(setup.N, poe[1].Q)
+	if PoKEStarVerify(pp, C[1], pokeStar[1]) || PoEVerifyInGroup(inZN, statements[1].Base, statements[1].C, statements[1].X, poe[1]) {
+		t.Fatalf("sign-flipped proof passes the single verifier")
+	}
+	for i := 0; i < 32; i++ {
+		if err = BatchPoKEStarVerify(pp, C, pokeStar); !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0] != 1 {
+			t.Fatalf("PoKE* batch accepts a sign-flipped proof in Z_N^*: %v", err)
+		}
+		if err = BatchPoEVerify(inZN, statements, poe); !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0] != 1 {
+			t.Fatalf("PoE batch accepts a sign-flipped proof in Z_N^*: %v", err)
+		}
+	}
+}
+
+func TestPuzzleVerify(t *testing.T) {
+	setup := TrustedSetup()
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	rsasetup := RSAExpSetup()
+	message := []byte("VTLP test message")
+	s := GenVRFSolution(message, rsasetup)
+	puzzle, err := rsasetup.NewPuzzle(s, 1000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	var commitment big.Int
+	commitment.Set(NewSignedQRGroup(pp.N).Exp(pp.G, s))
+	proof, err := PuzzleProve(&pp, message, s, puzzle, rsasetup)
+	if err != nil {
+		t.Errorf("error not empty for TestPuzzleVerify")
+	}
+	flag := PuzzleVerify(&pp, message, puzzle, &commitment, rsasetup.PublicPart(), proof)
+	if flag != true {
+		t.Errorf("did not pass verification")
+	}
+
+	flag = PuzzleVerify(&pp, []byte("another message"), puzzle, &commitment, rsasetup.PublicPart(), proof)
+	if flag == true {
+		t.Errorf("pass verification with a tampered message")
+	}
+	tampered := Puzzle{N: puzzle.N, T: puzzle.T, Z: new(big.Int).Add(puzzle.Z, big1)}
+	flag = PuzzleVerify(&pp, message, &tampered, &commitment, rsasetup.PublicPart(), proof)
+	if flag == true {
+		t.Errorf("pass verification with a tampered puzzle")
+	}
+	tampered = Puzzle{N: puzzle.N, T: puzzle.T + 1, Z: puzzle.Z}
+	flag = PuzzleVerify(&pp, message, &tampered, &commitment, rsasetup.PublicPart(), proof)
+	if flag == true {
+		t.Errorf("pass verification with a tampered time parameter")
+	}
+	another, err := rsasetup.NewPuzzle(GenVRFSolution([]byte("another message"), rsasetup), puzzle.T)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	flag = PuzzleVerify(&pp, message, another, &commitment, rsasetup.PublicPart(), proof)
+	if flag == true {
+		t.Errorf("pass verification with the puzzle of another message")
+	}
+	_, err = PuzzleProve(&pp, message, s, another, rsasetup)
+	if err == nil {
+		t.Errorf("error empty when the puzzle does not hide s")
+	}
+
+	// a prover who knows s and the trapdoor proves pi1 for the puzzle of another s, pi2 can not tie it to s
+	vrf := vrfValue(message, rsasetup.PublicPart())
+	forged := VTLPVRFProof{C2: pp.expG(new(big.Int).Exp(s, rsasetup.D, nil))}
+	transcript := vrfTranscript(&pp, another, &commitment, vrf, forged.C2, rsasetup.PublicPart())
+	if forged.pi1, err = zkpomodeFastProve(transcript.Fork("pi1"), &pp, &commitment, forged.C2, rsasetup.RSAMod, rsasetup.D, vrf, s); err != nil {
+		t.Fatalf("error not empty for zkpomodeFastProve")
+	}
+	derived, _ := vrfPuzzle(another, vrf, rsasetup.PublicPart())
+	_, forged.pi2 = rsasetup.ProveSolution(derived)
+	err = PuzzleVerifyErr(&pp, message, another, &commitment, rsasetup.PublicPart(), &forged)
+	var subErr *SubproofError
+	if !errors.As(err, &subErr) || subErr.Subproof != "pi2" {
+		t.Errorf("puzzle of another s is not rejected by pi2: %v", err)
+	}
+	forged.pi2.Pi.Set(proof.pi2.Pi)
+	if PuzzleVerify(&pp, message, another, &commitment, rsasetup.PublicPart(), &forged) {
+		t.Errorf("pass verification with the puzzle of another s")
+	}
+
+	s.Add(s, big1)
+	_, err = PuzzleProve(&pp, message, s, puzzle, rsasetup)
+	if err == nil {
+		t.Errorf("error empty when it should not for TestPuzzleVerify")
+	}
+}
+
+// encodedProof is implemented by every proof type in this package
+type encodedProof interface {
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary([]byte) error
+	MarshalJSON() ([]byte, error)
+	UnmarshalJSON([]byte) error
+	MarshalCBOR() ([]byte, error)
+	UnmarshalCBOR([]byte) error
+}
+
+type encodingTestCase struct {
+	name  string
+	proof encodedProof
+	empty func() encodedProof
+}
+
+// genEncodingTestCases returns one proof of every type in this package, the ZKPoMoDEProof is at index 6
+func genEncodingTestCases(t *testing.T, pp *PublicParameters, C1, n, e, xmod, x *big.Int) []encodingTestCase {
+	proof, err := ZKPoMoDEProve(pp, C1, n, e, xmod, x)
+	if err != nil {
+		t.Fatalf("error not empty for ZKPoMoDEProve")
+	}
+	rsasetup := RSAExpSetup()
+	message := []byte("VTLP test message")
+	s := GenVRFSolution(message, rsasetup)
+	puzzle, err := rsasetup.NewPuzzle(s, 1000)
+	if err != nil {
+		t.Fatalf("error not empty for NewPuzzle")
+	}
+	vrfProof, err := PuzzleProve(pp, message, s, puzzle, rsasetup)
+	if err != nil {
+		t.Fatalf("error not empty for PuzzleProve")
+	}
+	_, wesolowski, err := SolvePuzzleWithProof(context.Background(), puzzle, nil)
+	if err != nil {
+		t.Fatalf("error not empty for SolvePuzzleWithProof")
+	}
+	_, pietrzak, err := SolvePuzzleWithProof(context.Background(), puzzle, &SolveOptions{VDF: PietrzakVDF{}})
+	if err != nil {
+		t.Fatalf("error not empty for SolvePuzzleWithProof")
+	}
+	return []encodingTestCase{
+		{"PoKEStar", proof.pi1, func() encodedProof { return new(PoKEStarProof) }},
+		{"ZKPoKE", proof.pi2.pi3, func() encodedProof { return new(ZKPoKEProof) }},
+		{"PoE", proof.pi2.pi2, func() encodedProof { return new(PoEProof) }},
+		{"PoKDE", proof.pi2.pi4, func() encodedProof { return new(PoKDEProof) }},
+		{"ZKPoKDE", proof.pi2, func() encodedProof { return new(ZKPoKDEProof) }},
+		{"ZKPoKEMod", proof.pi3, func() encodedProof { return new(ZKPoKEModProof) }},
+		{"ZKPoMoDE", proof, func() encodedProof { return new(ZKPoMoDEProof) }},
+		{"ZKPoMoDEFast", vrfProof.pi1, func() encodedProof { return new(ZKPoMoDEFastProof) }},
+		{"VTLPVRF", vrfProof, func() encodedProof { return new(VTLPVRFProof) }},
+		{"Wesolowski", wesolowski.(*WesolowskiProof), func() encodedProof { return new(WesolowskiProof) }},
+		{"Pietrzak", pietrzak.(*PietrzakProof), func() encodedProof { return new(PietrzakProof) }},
+	}
+}
+
+func TestProofEncoding(t *testing.T) {
+	setup := TrustedSetup()
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	var x, C1, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	testCases := genEncodingTestCases(t, &pp, &C1, &n, &e, &xmod, &x)
+	for _, tc := range testCases {
+		data, err := tc.proof.MarshalBinary()
+		if err != nil {
+			t.Errorf("%s: failed to marshal: %v", tc.name, err)
+			continue
+		}
+		decoded := tc.empty()
+		if err = decoded.UnmarshalBinary(data); err != nil {
+			t.Errorf("%s: failed to unmarshal: %v", tc.name, err)
+			continue
+		}
+		again, err := decoded.MarshalBinary()
+		if err != nil || !bytes.Equal(data, again) {
+			t.Errorf("%s: encoding is not canonical", tc.name)
+		}
+		if tc.empty().UnmarshalBinary(append(data, 0)) == nil {
+			t.Errorf("%s: accepted trailing bytes", tc.name)
+		}
+		if tc.empty().UnmarshalBinary(data[:len(data)-1]) == nil {
+			t.Errorf("%s: accepted truncated encoding", tc.name)
+		}
+		wrongVersion := append([]byte{}, data...)
+		wrongVersion[0]++
+		if tc.empty().UnmarshalBinary(wrongVersion) == nil {
+			t.Errorf("%s: accepted a wrong version", tc.name)
+		}
+		for version := byte(1); version < ProofEncodingVersion; version++ {
+			wrongVersion[0] = version
+			if tc.empty().UnmarshalBinary(wrongVersion) == nil {
+				t.Errorf("%s: accepted a proof of version %d", tc.name, version)
+			}
+		}
+		// insert a leading zero byte into the first integer or nested proof
+		nonMinimal := append([]byte{}, data[:2]...)
+		length := binary.BigEndian.Uint32(data[2:])
+		nonMinimal = binary.BigEndian.AppendUint32(nonMinimal, length+1)
+		nonMinimal = append(nonMinimal, 0)
+		nonMinimal = append(nonMinimal, data[6:]...)
+		if tc.empty().UnmarshalBinary(nonMinimal) == nil {
+			t.Errorf("%s: accepted a non-minimal encoding", tc.name)
+		}
+	}
+
+	var decoded ZKPoMoDEProof
+	data, _ := testCases[6].proof.MarshalBinary()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("failed to unmarshal ZKPoMoDEProof")
+	}
+	if !ZKPoMoDEVerify(&pp, &C1, &n, &e, &xmod, &decoded) {
+		t.Errorf("decoded proof did not pass verification")
+	}
+	var wrongType PoEProof
+	if wrongType.UnmarshalBinary(data) == nil {
+		t.Errorf("accepted the encoding of another proof type")
+	}
+
+	// the decoders of the proofs in the group of pp accept their encodings with pp
+	forParameters := map[string]interface {
+		UnmarshalBinaryFor(*PublicParameters, []byte) error
+	}{
+		"PoKEStar": new(PoKEStarProof), "ZKPoKE": new(ZKPoKEProof), "PoKDE": new(PoKDEProof),
+		"ZKPoKDE": new(ZKPoKDEProof), "ZKPoKEMod": new(ZKPoKEModProof), "ZKPoMoDE": new(ZKPoMoDEProof),
+		"ZKPoMoDEFast": new(ZKPoMoDEFastProof), "VTLPVRF": new(VTLPVRFProof),
+	}
+	for _, tc := range testCases {
+		decoder, ok := forParameters[tc.name]
+		if !ok {
+			continue
+		}
+		data, _ := tc.proof.MarshalBinary()
+		if err := decoder.UnmarshalBinaryFor(&pp, data); err != nil {
+			t.Errorf("%s: failed to unmarshal with the public parameters: %v", tc.name, err)
+		}
+	}
+
+	// out-of-range values are rejected on decoding, elements of N or above once the group is known
+	pokeStar := testCases[0].proof.(*PoKEStarProof)
+	outOfRange := []struct {
+		name    string
+		proof   PoKEStarProof
+		decodes bool
+	}{
+		{"element N", PoKEStarProof{Q: setup.N, R: pokeStar.R}, true},
+		{"element N+Q", PoKEStarProof{Q: new(big.Int).Add(setup.N, pokeStar.Q), R: pokeStar.R}, true},
+		{"element N-Q", PoKEStarProof{Q: new(big.Int).Sub(setup.N, pokeStar.Q), R: pokeStar.R}, true},
+		{"element 0", PoKEStarProof{Q: new(big.Int), R: pokeStar.R}, false},
+		{"oversized remainder", PoKEStarProof{Q: pokeStar.Q, R: new(big.Int).Lsh(big1, maxChallengeBits)}, false},
+	}
+	for _, tc := range outOfRange {
+		data, err := tc.proof.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: failed to marshal: %v", tc.name, err)
+		}
+		var decoded PoKEStarProof
+		if err = decoded.UnmarshalBinary(data); (err == nil) != tc.decodes {
+			t.Errorf("%s: UnmarshalBinary returns %v", tc.name, err)
+		}
+		if err = decoded.UnmarshalBinaryFor(&pp, data); !errors.Is(err, errElementRange) && !errors.Is(err, errRemainderLength) {
+			t.Errorf("%s: UnmarshalBinaryFor returns %v", tc.name, err)
+		}
+	}
+	if _, err := (&PoKEStarProof{Q: pokeStar.Q, R: big.NewInt(-1)}).MarshalBinary(); !errors.Is(err, errNegativeInteger) {
+		t.Errorf("negative remainder is encoded")
+	}
+	var poe PoEProof
+	data, _ = (&PoEProof{Q: setup.N}).MarshalBinary()
+	if err := poe.UnmarshalBinaryFor(NewRSAGroup(setup.N), data); !errors.Is(err, errElementRange) {
+		t.Errorf("PoE element N is decoded in the RSA group: %v", err)
+	}
+	var ciphertext TimeLockCiphertext
+	for _, Z := range []*big.Int{new(big.Int), setup.N, new(big.Int).Add(setup.N, big1)} {
+		data, _ = (&TimeLockCiphertext{Puzzle: &Puzzle{N: setup.N, T: 10, Z: Z}, Nonce: []byte{1}, Ciphertext: []byte{2}}).MarshalBinary()
+		if err := ciphertext.UnmarshalBinary(data); !errors.Is(err, errElementRange) {
+			t.Errorf("ciphertext of a puzzle with Z = %v is decoded: %v", Z, err)
+		}
+	}
+}
+
+func TestProofJSONAndCBOR(t *testing.T) {
+	setup := TrustedSetup()
+	pp := PublicParameters{N: setup.N, G: setup.G, H: setup.H}
+	var x, C1, e, x2e, n, xmod big.Int
+	x.SetInt64(6)
+	e.SetInt64(7)
+	n.SetInt64(10)
+	x2e.Exp(&x, &e, nil)
+	xmod.Mod(&x2e, &n)
+	C1.Set(NewSignedQRGroup(setup.N).Exp(setup.G, &x))
+	for _, tc := range genEncodingTestCases(t, &pp, &C1, &n, &e, &xmod, &x) {
+		want, _ := tc.proof.MarshalBinary()
+		data, err := json.Marshal(tc.proof)
+		if err != nil {
+			t.Errorf("%s: failed to marshal JSON: %v", tc.name, err)
+			continue
+		}
+		decoded := tc.empty()
+		if err = json.Unmarshal(data, decoded); err != nil {
+			t.Errorf("%s: failed to unmarshal JSON: %v", tc.name, err)
+			continue
+		}
+		got, _ := decoded.MarshalBinary()
+		if !bytes.Equal(want, got) {
+			t.Errorf("%s: JSON round trip changed the proof", tc.name)
+		}
+
+		data, err = cbor.Marshal(tc.proof)
+		if err != nil {
+			t.Errorf("%s: failed to marshal CBOR: %v", tc.name, err)
+			continue
+		}
+		decoded = tc.empty()
+		if err = cbor.Unmarshal(data, decoded); err != nil {
+			t.Errorf("%s: failed to unmarshal CBOR: %v", tc.name, err)
+			continue
+		}
+		got, _ = decoded.MarshalBinary()
+		if !bytes.Equal(want, got) {
+			t.Errorf("%s: CBOR round trip changed the proof", tc.name)
+		}
+	}
+
+	data, _ := json.Marshal(&pp)
+	var decodedPP PublicParameters
+	if err := json.Unmarshal(data, &decodedPP); err != nil || decodedPP.N.Cmp(pp.N) != 0 ||
+		decodedPP.G.Cmp(pp.G) != 0 || decodedPP.H.Cmp(pp.H) != 0 {
+		t.Errorf("JSON round trip changed the public parameters")
+	}
+	data, _ = cbor.Marshal(setup)
+	var decodedSetup Setup
+	if err := cbor.Unmarshal(data, &decodedSetup); err != nil || decodedSetup.N.Cmp(setup.N) != 0 ||
+		decodedSetup.G.Cmp(setup.G) != 0 || decodedSetup.H.Cmp(setup.H) != 0 {
+		t.Errorf("CBOR round trip changed the setup")
+	}
+	rsaPublic := RSAExpSetup().PublicPart()
+	data, _ = json.Marshal(rsaPublic)
+	var decodedRSA RSAExpPublic
+	if err := json.Unmarshal(data, &decodedRSA); err != nil || decodedRSA.RSAMod.Cmp(rsaPublic.RSAMod) != 0 ||
+		decodedRSA.D.Cmp(rsaPublic.D) != 0 {
+		t.Errorf("JSON round trip changed the RSA public key")
+	}
+	data, _ = cbor.Marshal(rsaPublic)
+	if err := cbor.Unmarshal(data, &decodedRSA); err != nil || decodedRSA.RSAMod.Cmp(rsaPublic.RSAMod) != 0 {
+		t.Errorf("CBOR round trip changed the RSA public key")
+	}
+
+	invalid := []string{
+		`{"version":2,"n":"0x1","g":"0x1","h":"0x1"}`,
+		`{"version":1,"n":"0x01","g":"0x1","h":"0x1"}`,
+		`{"version":1,"n":"0xA","g":"0x1","h":"0x1"}`,
+		`{"version":1,"n":"10","g":"0x1","h":"0x1"}`,
+		`{"version":1,"g":"0x1","h":"0x1"}`,
+	}
+	for _, input := range invalid {
+		if json.Unmarshal([]byte(input), &decodedPP) == nil {
+			t.Errorf("accepted invalid public parameters %s", input)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package protocol
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Puzzle is a time-lock puzzle with hardness T, its solution Z^{2^T} takes T sequential squarings to compute. The puzzle
+// runs in Group, or in QR_N^+ if Group is nil, where the solution of an RSA puzzle is |Z^{2^T} mod N|. Only RSA puzzles
+// have a trapdoor, puzzles in a class group need no trusted setup.
+type Puzzle struct {
+	N     *big.Int
+	T     int64
+	Z     *big.Int
+	Group Group
+}
+
+// NewGroupPuzzle returns a puzzle with hardness T whose input is the element Z of group. Without a trapdoor nobody
+// knows the solution before the T squarings are done.
+func NewGroupPuzzle(group Group, Z *big.Int, T int64) (*Puzzle, error) {
+	if T <= 0 {
+		return nil, errors.New("NewGroupPuzzle requires a positive time parameter")
+	}
+	if !group.IsElement(Z) {
+		return nil, errors.New("NewGroupPuzzle requires a group element as input")
+	}
+	return &Puzzle{T: T, Z: new(big.Int).Set(Z), Group: group}, nil
+}
+
+// group returns the group of the puzzle
+func (puzzle *Puzzle) group() Group {
+	if puzzle.Group != nil {
+		return puzzle.Group
+	}
+	return NewSignedQRGroup(puzzle.N)
+}
+
+// NewPuzzle generates an RSA puzzle with hardness T whose solution is |s|. Squaring is a permutation of QR_N, so s or
+// -s must be a quadratic residue mod RSAMod.
+func (setup *RSAExpProof) NewPuzzle(s *big.Int, T int64) (*Puzzle, error) {
+	if T <= 0 {
+		return nil, errors.New("NewPuzzle requires a positive time parameter")
+	}
+	if s == nil || s.Sign() <= 0 || s.Cmp(setup.RSAMod) >= 0 {
+		return nil, errors.New("NewPuzzle requires a quadratic residue as solution")
+	}
+	// -1 is a non-residue, so at most one of s and -s is a residue
+	residue := new(big.Int).Set(s)
+	if !isQR(residue, setup.P, setup.Q) {
+		residue.Sub(setup.RSAMod, s)
+		if !isQR(residue, setup.P, setup.Q) {
+			return nil, errors.New("NewPuzzle requires a quadratic residue as solution")
+		}
+	}
+	group := NewSignedQRGroup(setup.RSAMod)
+	return &Puzzle{
+		N: new(big.Int).Set(setup.RSAMod),
+		T: T,
+		Z: group.Exp(residue, setup.inverseTimeExponent(T)),
+	}, nil
+}
+
+// Solution computes the solution |Z^{2^T} mod N| of the puzzle with the trapdoor, which takes one exponentiation instead
+// of T squarings
+func (setup *RSAExpProof) Solution(puzzle *Puzzle) *big.Int {
+	// Z^2 is in QR_N whose order is Order, so Z^{2^T} = (Z^2)^{2^{T-1} mod Order} and 2^{T-1} = 2^T * (Order+1)/2 mod Order
+	var z2, half, exp big.Int
+	z2.Exp(puzzle.Z, big2, setup.RSAMod)
+	half.Add(setup.Order, big1)
+	half.Rsh(&half, 1)
+	exp.Mul(setup.TimeExponent(puzzle.T), &half)
+	exp.Mod(&exp, setup.Order)
+	return NewSignedQRGroup(setup.RSAMod).Exp(&z2, &exp)
+}
+
+// ProveSolution computes the solution of the puzzle and its Wesolowski proof with the trapdoor. QR_N^+ has order Order, so
+// Pi = Z^{floor(2^T/l)} is one exponentiation by (2^T - (2^T mod l)) * l^{-1} mod Order instead of T squarings.
+func (setup *RSAExpProof) ProveSolution(puzzle *Puzzle) (*big.Int, *WesolowskiProof) {
+	y := setup.Solution(puzzle)
+	l := wesolowskiChallenge(puzzle, y)
+	var r, exp big.Int
+	r.Exp(big2, big.NewInt(puzzle.T), l)
+	exp.Sub(setup.TimeExponent(puzzle.T), &r)
+	exp.Mul(&exp, r.ModInverse(l, setup.Order))
+	exp.Mod(&exp, setup.Order)
+	return y, &WesolowskiProof{Pi: NewSignedQRGroup(setup.RSAMod).Exp(puzzle.Z, &exp)}
+}
@@ -0,0 +1,168 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+const (
+	// sieveBound bounds the small primes r of the combined sieve
+	sieveBound = 1 << 14
+	// minSieveBits is the smallest safe prime the sieve is used for, below it p' could be one of the small primes
+	minSieveBits = 20
+	// maxSieveSteps is the range of candidates searched from one random starting point before drawing a new one
+	maxSieveSteps = 1 << 16
+)
+
+var (
+	smallPrimesOnce sync.Once
+	smallPrimes     []uint64
+)
+
+// getSmallPrimes returns the odd primes below sieveBound
+func getSmallPrimes() []uint64 {
+	smallPrimesOnce.Do(func() {
+		composite := make([]bool, sieveBound)
+		for i := uint64(3); i < sieveBound; i += 2 {
+			if composite[i] {
+				continue
+			}
+			smallPrimes = append(smallPrimes, i)
+			for j := i * i; j < sieveBound; j += 2 * i {
+				composite[j] = true
+			}
+		}
+	})
+	return smallPrimes
+}
+
+// lockedReader serializes the reads of the workers from a shared source of randomness
+type lockedReader struct {
+	lock   sync.Mutex
+	reader io.Reader
+}
+
+func (r *lockedReader) Read(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.reader.Read(p)
+}
+
+// GenerateSafePrime returns a random safe prime p = 2p' +1 of bits bits where p' is also a prime number.
+// The candidates are searched on all CPUs.
+func GenerateSafePrime(ctx context.Context, random io.Reader, bits int) (*big.Int, error) {
+	return generateSafePrime(ctx, random, bits, runtime.NumCPU())
+}
+
+func generateSafePrime(ctx context.Context, random io.Reader, bits, workers int) (*big.Int, error) {
+	if bits < 3 {
+		return nil, errors.New("a safe prime needs at least 3 bits")
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	var primes []uint64
+	if bits >= minSieveBits {
+		primes = getSmallPrimes()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	reader := &lockedReader{reader: random}
+	// buffered, so that the workers can exit after the first result is taken
+	results := make(chan *big.Int, workers)
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			ret, err := searchSafePrime(ctx, reader, bits, primes)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- ret
+		}()
+	}
+	var firstErr error
+	for i := 0; i < workers; i++ {
+		select {
+		case ret := <-results:
+			return ret, nil
+		case err := <-errs:
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return nil, firstErr
+}
+
+// searchSafePrime implements the method described in "Safe Prime Generation with a Combined Sieve": starting from a random
+// odd p', it skips every candidate with p' = 0 or p' = (r-1)/2 mod a small prime r, as then r divides p' or p = 2p'+1.
+// The survivors are filtered with a Fermat test in base 2 before the Miller-Rabin tests.
+func searchSafePrime(ctx context.Context, random io.Reader, bits int, primes []uint64) (*big.Int, error) {
+	residues := make([]uint64, len(primes))
+	var candidate, p, exp, temp, r big.Int
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		start, err := randomTopBits(random, bits-1)
+		if err != nil {
+			return nil, err
+		}
+		for i, prime := range primes {
+			residues[i] = temp.Mod(start, r.SetUint64(prime)).Uint64()
+		}
+		for step := uint64(0); step < maxSieveSteps; step += 2 {
+			if !sievePasses(residues, primes, step) {
+				continue
+			}
+			if err = ctx.Err(); err != nil {
+				return nil, err
+			}
+			candidate.Add(start, temp.SetUint64(step))
+			if candidate.BitLen() != bits-1 {
+				break
+			}
+			p.Lsh(&candidate, 1)
+			p.Add(&p, big1)
+			exp.Lsh(&candidate, 1)
+			if temp.Exp(big2, &exp, &p).Cmp(big1) != 0 {
+				continue
+			}
+			if candidate.ProbablyPrime(20) && p.ProbablyPrime(securityPara/2) {
+				return new(big.Int).Set(&p), nil
+			}
+		}
+	}
+}
+
+// sievePasses returns false if a small prime divides p' = start+step or 2p'+1, residues[i] = start mod primes[i]
+func sievePasses(residues, primes []uint64, step uint64) bool {
+	for i, prime := range primes {
+		x := (residues[i] + step) % prime
+		if x == 0 || x == prime/2 {
+			return false
+		}
+	}
+	return true
+}
+
+// randomTopBits returns a random odd integer of bits bits with the two most significant bits set
+func randomTopBits(random io.Reader, bits int) (*big.Int, error) {
+	buf := make([]byte, (bits+7)/8)
+	if _, err := io.ReadFull(random, buf); err != nil {
+		return nil, err
+	}
+	ret := new(big.Int).SetBytes(buf)
+	ret.Rsh(ret, uint(len(buf)*8-bits))
+	ret.SetBit(ret, bits-1, 1)
+	if bits >= 2 {
+		ret.SetBit(ret, bits-2, 1)
+	}
+	ret.SetBit(ret, 0, 1)
+	return ret, nil
+}
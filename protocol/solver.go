@@ -0,0 +1,264 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"os"
+)
+
+// ctxCheckInterval is the number of squarings between two checks of the context
+const ctxCheckInterval = 1 << 10
+
+var errCheckpointMismatch = errors.New("checkpoint belongs to another puzzle")
+
+// SolveOptions configures SolvePuzzle, the zero value solves the puzzle without progress reports and checkpoints
+type SolveOptions struct {
+	// Progress is called with the number of squarings done every ProgressInterval squarings and once the puzzle is solved
+	Progress         func(done, total int64)
+	ProgressInterval int64
+	// CheckpointPath is the file the intermediate value is written to every CheckpointInterval squarings and when the context
+	// is cancelled. An existing checkpoint of the same puzzle at CheckpointPath is resumed.
+	CheckpointPath     string
+	CheckpointInterval int64
+	// VDF proves the solution in SolvePuzzleWithProof, WesolowskiVDF is used if it is nil
+	VDF VDF
+}
+
+// solverState is the intermediate value Y = Z^{2^Done} of a puzzle. If interval is positive, points keeps Z^{2^{ik}}
+// for every ik < Done with k = interval, these are needed for the proof of the solution.
+type solverState struct {
+	puzzle   *Puzzle
+	done     int64
+	y        *big.Int
+	interval int64
+	points   []*big.Int
+	// binding is the binding of the group of a decoded checkpoint, whose puzzle has no group yet
+	binding []byte
+}
+
+func (state *solverState) MarshalBinary() ([]byte, error) {
+	return marshalProof(tagCheckpoint, func(enc *proofEncoder) error {
+		enc.writeBytes(state.puzzle.group().Binding())
+		err := enc.writeInts(big.NewInt(state.puzzle.T), state.puzzle.Z, big.NewInt(state.done), state.y,
+			big.NewInt(state.interval), big.NewInt(int64(len(state.points))))
+		if err != nil {
+			return err
+		}
+		return enc.writeInts(state.points...)
+	})
+}
+
+func (state *solverState) UnmarshalBinary(data []byte) error {
+	var binding []byte
+	var T, Z, done, y, interval, count *big.Int
+	var points []*big.Int
+	err := unmarshalProof(data, tagCheckpoint, func(dec *proofDecoder) error {
+		var err error
+		if binding, err = dec.readBytes(); err != nil {
+			return err
+		}
+		if err = dec.readInts(&T, &Z, &done, &y, &interval, &count); err != nil {
+			return err
+		}
+		if !count.IsInt64() || count.Int64() > maxProofPoints {
+			return errors.New("checkpoint is invalid")
+		}
+		points = make([]*big.Int, count.Int64())
+		for i := range points {
+			if err := dec.readInts(&points[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !T.IsInt64() || !done.IsInt64() || !interval.IsInt64() || done.Int64() > T.Int64() {
+		return errors.New("checkpoint is invalid")
+	}
+	if interval.Sign() > 0 && int64(len(points)) != (done.Int64()+interval.Int64()-1)/interval.Int64() {
+		return errors.New("checkpoint is invalid")
+	}
+	state.puzzle = &Puzzle{T: T.Int64(), Z: Z}
+	state.binding = binding
+	state.done = done.Int64()
+	state.y = y
+	state.interval = interval.Int64()
+	state.points = points
+	return nil
+}
+
+// loadCheckpoint returns the state stored at path, or the initial state if there is no checkpoint yet
+func loadCheckpoint(path string, puzzle *Puzzle, interval int64) (*solverState, error) {
+	initial := &solverState{puzzle: puzzle, y: puzzle.Z, interval: interval}
+	if path == "" {
+		return initial, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return initial, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state solverState
+	if err = state.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	group := puzzle.group()
+	if state.puzzle.T != puzzle.T || !bytes.Equal(state.binding, group.Binding()) || state.puzzle.Z.Cmp(puzzle.Z) != 0 ||
+		state.interval != interval {
+		return nil, errCheckpointMismatch
+	}
+	if !group.IsElement(state.y) {
+		return nil, errors.New("checkpoint is invalid")
+	}
+	for _, point := range state.points {
+		if !group.IsElement(point) {
+			return nil, errors.New("checkpoint is invalid")
+		}
+	}
+	state.puzzle = puzzle
+	return &state, nil
+}
+
+// storeCheckpoint writes the state to a temporary file first, so that a crash never leaves a truncated checkpoint at path
+func storeCheckpoint(path string, state *solverState) error {
+	data, err := state.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	if err = os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// SolvePuzzle solves a time-lock puzzle without the trapdoor by T sequential squarings of Z.
+// This function takes a long time to solve! It returns ctx.Err() if ctx is cancelled, after storing a checkpoint if configured.
+func SolvePuzzle(ctx context.Context, puzzle *Puzzle, opts *SolveOptions) (*big.Int, error) {
+	state, err := solve(ctx, puzzle, opts, 0)
+	if err != nil {
+		return nil, err
+	}
+	return state.y, nil
+}
+
+// SolvePuzzleWithProof solves a time-lock puzzle like SolvePuzzle and proves the solution with opts.VDF, so that
+// verifiers do not need to redo the squarings. Keeping the intermediate values for the proof needs a few MB of memory.
+func SolvePuzzleWithProof(ctx context.Context, puzzle *Puzzle, opts *SolveOptions) (*big.Int, VDFProof, error) {
+	interval := int64(1)
+	if puzzle != nil {
+		interval = proofInterval(puzzle.T)
+	}
+	state, err := solve(ctx, puzzle, opts, interval)
+	if err != nil {
+		return nil, nil, err
+	}
+	var vdf VDF = WesolowskiVDF{}
+	if opts != nil && opts.VDF != nil {
+		vdf = opts.VDF
+	}
+	proof, err := vdf.Prove(puzzle, state.y, state.points, interval)
+	if err != nil {
+		return nil, nil, err
+	}
+	return state.y, proof, nil
+}
+
+// puzzleSquarer squares the intermediate value of a puzzle
+type puzzleSquarer interface {
+	Square()
+	Int() *big.Int
+}
+
+// groupSquarer squares with the Mul of a group
+type groupSquarer struct {
+	group Group
+	x     *big.Int
+}
+
+func (squarer *groupSquarer) Square() {
+	squarer.x = squarer.group.Mul(squarer.x, squarer.x)
+}
+
+func (squarer *groupSquarer) Int() *big.Int {
+	return squarer.x
+}
+
+// rsaSquarer squares with a Squarer mod N and maps the residues to the elements of the RSA group of the puzzle
+type rsaSquarer struct {
+	*Squarer
+	group Group
+}
+
+func (squarer rsaSquarer) Int() *big.Int {
+	return squarer.group.Mul(squarer.Squarer.Int(), squarer.group.Identity())
+}
+
+// newPuzzleSquarer returns the squarer of the intermediate value y of puzzle, RSA puzzles are squared with a Squarer
+func newPuzzleSquarer(puzzle *Puzzle, y *big.Int) puzzleSquarer {
+	if puzzle.Group == nil {
+		return rsaSquarer{Squarer: NewSquarer(puzzle.N, y), group: puzzle.group()}
+	}
+	return &groupSquarer{group: puzzle.Group, x: y}
+}
+
+// solve performs the squarings, keeping Z^{2^{ik}} for k = interval if interval is positive
+func solve(ctx context.Context, puzzle *Puzzle, opts *SolveOptions, interval int64) (*solverState, error) {
+	if puzzle == nil || puzzle.Z == nil || (puzzle.Group == nil && (puzzle.N == nil || puzzle.N.Sign() <= 0)) {
+		return nil, errors.New("SolvePuzzle inputs an empty puzzle")
+	}
+	if puzzle.T <= 0 {
+		return nil, errors.New("SolvePuzzle requires a positive time parameter")
+	}
+	if opts == nil {
+		opts = &SolveOptions{}
+	}
+	state, err := loadCheckpoint(opts.CheckpointPath, puzzle, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// state.y is only brought up to date with the squarer before it is stored or returned
+	squarer := newPuzzleSquarer(puzzle, state.y)
+	for state.done < puzzle.T {
+		if state.done%ctxCheckInterval == 0 && ctx.Err() != nil {
+			if opts.CheckpointPath != "" {
+				state.y = squarer.Int()
+				if err = storeCheckpoint(opts.CheckpointPath, state); err != nil {
+					return nil, err
+				}
+			}
+			return nil, ctx.Err()
+		}
+		if interval > 0 && state.done%interval == 0 {
+			state.points = append(state.points, squarer.Int())
+		}
+		squarer.Square()
+		state.done++
+		if opts.Progress != nil && opts.ProgressInterval > 0 && state.done%opts.ProgressInterval == 0 && state.done < puzzle.T {
+			opts.Progress(state.done, puzzle.T)
+		}
+		if opts.CheckpointPath != "" && opts.CheckpointInterval > 0 && state.done%opts.CheckpointInterval == 0 {
+			state.y = squarer.Int()
+			if err = storeCheckpoint(opts.CheckpointPath, state); err != nil {
+				return nil, err
+			}
+		}
+	}
+	state.y = squarer.Int()
+	if opts.CheckpointPath != "" {
+		if err = storeCheckpoint(opts.CheckpointPath, state); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(puzzle.T, puzzle.T)
+	}
+	return state, nil
+}
@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"math/big"
+)
+
+// squarerMaxLimbs is the widest modulus squared in Montgomery arithmetic. The portable Montgomery squaring is about 15
+// percent faster than math/big at 2048 and 3072 bits and falls behind the assembly of math/big from 4096 bits on.
+const squarerMaxLimbs = 48
+
+// Squarer repeatedly squares a value modulo N without allocating, it is the hot loop of solving time-lock puzzles.
+// Odd moduli of up to 48 words, 3072 bits on 64-bit platforms, are squared in fixed-width Montgomery arithmetic, other
+// moduli with math/big on preallocated buffers. A Squarer is not safe for concurrent use.
+type Squarer struct {
+	N *big.Int
+	// mont is nil if the value is squared with math/big
+	mont *montgomery
+	// x is the Montgomery representation of the value
+	x []uint64
+	// y is the value if mont is nil, square and quotient are the buffers of the squaring
+	y, square, quotient big.Int
+}
+
+// NewSquarer returns a Squarer of x mod N for a positive N
+func NewSquarer(N, x *big.Int) *Squarer {
+	ret := &Squarer{N: N}
+	if (N.BitLen()+63)/64 <= squarerMaxLimbs {
+		ret.mont, _ = newMontgomery(N)
+	}
+	ret.Set(x)
+	return ret
+}
+
+// Set sets the value to x mod N
+func (squarer *Squarer) Set(x *big.Int) {
+	if squarer.mont != nil {
+		squarer.x = squarer.mont.toMontgomery(x)
+		return
+	}
+	squarer.y.Mod(x, squarer.N)
+	// the product of two residues needs twice the words of N
+	squarer.square.Mul(squarer.N, squarer.N)
+	squarer.quotient.Set(squarer.N)
+}
+
+// Int returns the value
+func (squarer *Squarer) Int() *big.Int {
+	if squarer.mont != nil {
+		return squarer.mont.fromMontgomery(squarer.x)
+	}
+	return new(big.Int).Set(&squarer.y)
+}
+
+// Square squares the value
+func (squarer *Squarer) Square() {
+	if squarer.mont != nil {
+		squarer.mont.square(squarer.x, squarer.x)
+		return
+	}
+	squarer.square.Mul(&squarer.y, &squarer.y)
+	squarer.quotient.QuoRem(&squarer.square, squarer.N, &squarer.y)
+}
+
+// SquareN squares the value n times
+func (squarer *Squarer) SquareN(n int64) {
+	for i := int64(0); i < n; i++ {
+		squarer.Square()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math/big"
 
+	fiatshamir "github.com/VTLP/fiat-shamir"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 )
 
@@ -14,6 +15,18 @@ func GenVRF(message []byte, rsasetup *RSAExpProof) *big.Int {
 	return DIHashPoseidon(&element)
 }
 
+// vrfValue returns the VRF value proved by PuzzleProve, the hash of GenVRF squared mod RSAMod, so that it is a
+// quadratic residue and its root can be the solution of a Puzzle
+func vrfValue(message []byte, rsasetup *RSAExpPublic) *big.Int {
+	ret := GenVRF(message, nil)
+	return ret.Exp(ret, big2, rsasetup.RSAMod)
+}
+
+// GenVRFSolution evaluates the RSA-based VRF on message with the private key of rsasetup, the result s satisfies s^D = GenVRF(message)^2 mod RSAMod
+func GenVRFSolution(message []byte, rsasetup *RSAExpProof) *big.Int {
+	return new(big.Int).Exp(vrfValue(message, rsasetup.PublicPart()), rsasetup.E, rsasetup.RSAMod)
+}
+
 // GenPuzzle generates a time-lock puzzle using the parameters of RSAExpProof, s is the solution
 func GenPuzzle(s *big.Int, rsasetup *RSAExpProof) *big.Int {
 	var ret big.Int
@@ -21,37 +34,115 @@ func GenPuzzle(s *big.Int, rsasetup *RSAExpProof) *big.Int {
 	return &ret
 }
 
-// SolvePuzzle solves a time-lcok puuzle with Time parameter with respect to public key N
-// This function takes a long time to solve!
-func SolvePuzzle(z, N *big.Int) *big.Int {
-	var ret, exp, temp big.Int
-	temp.SetInt64(TimePara)
-	exp.Exp(big2, &temp, N)
-	ret.Exp(z, &exp, N)
-	return &ret
-}
-
-// VTLPVRFProof contains the proofs for proving a time-lock puzzle
+// VTLPVRFProof contains the proofs for proving a time-lock puzzle, pi1 proves that the committed s is a root of the VRF value
+// and pi2 that the puzzle hides this root
 type VTLPVRFProof struct {
+	C2  *big.Int
 	pi1 *ZKPoMoDEFastProof
+	pi2 *WesolowskiProof
 }
 
-func PuzzleProve(pp *PublicParameters, message []byte, s *big.Int, rsasetup *RSAExpProof) (*VTLPVRFProof, error) {
+func (proof *VTLPVRFProof) isEmpty() bool {
+	if proof.C2 == nil || proof.pi1 == nil || proof.pi2 == nil || proof.pi1.isEmpty() || proof.pi2.isEmpty() {
+		return true
+	}
+	return false
+}
+
+// Validate checks that commitment and C2 are group elements, that the RSA public key is present and that the puzzle
+// is a puzzle of the RSA modulus with Z in QR_N^+
+func (proof *VTLPVRFProof) Validate(pp *PublicParameters, puzzle *Puzzle, commitment *big.Int, rsasetup *RSAExpPublic) error {
+	v := newValidator("VTLPVRF", nil)
+	v.parameters(pp)
+	v.element("commitment", commitment)
+	if v.present("proof", proof != nil) {
+		v.element("C2", proof.C2)
+		v.present("pi1", proof.pi1 != nil)
+		v.present("pi2", proof.pi2 != nil)
+	}
+	if v.present("rsasetup", rsasetup != nil) {
+		v.positive("RSAMod", rsasetup.RSAMod)
+		v.positive("D", rsasetup.D)
+	}
+	v.puzzle(puzzle)
+	if v.err == nil && (puzzle.Group != nil || puzzle.N.Cmp(rsasetup.RSAMod) != 0) {
+		v.fail("N", "is not the RSA modulus")
+	}
+	return v.result()
+}
+
+// vrfTranscript returns the transcript of a VTLPVRF proof after its statement and C2, pi1 is forked from it so that it
+// is bound to the puzzle and the VRF value
+func vrfTranscript(pp *PublicParameters, puzzle *Puzzle, commitment, vrf, C2 *big.Int,
+	rsasetup *RSAExpPublic) *fiatshamir.Transcript {
+	transcript := newTranscript(nil, "VTLPVRF", pp.group())
+	transcript.AppendInts("G", pp.G)
+	transcript.AppendInts("RSAMod", rsasetup.RSAMod)
+	transcript.AppendInts("D", rsasetup.D)
+	transcript.AppendInts("T", big.NewInt(puzzle.T))
+	transcript.AppendInts("Z", puzzle.Z)
+	transcript.AppendInts("commitment", commitment)
+	transcript.AppendInts("vrf", vrf)
+	transcript.AppendInts("C2", C2)
+	return transcript
+}
+
+// vrfPuzzle returns the puzzle Z' = |Z^D| and its claimed solution |vrf|. Raising to the odd D permutes Z_N^* and maps
+// -1 to -1, so |Z'^{2^T}| = |vrf| holds exactly if Z^{2^T} = ±s for the root s of vrf = s^D mod RSAMod.
+func vrfPuzzle(puzzle *Puzzle, vrf *big.Int, rsasetup *RSAExpPublic) (*Puzzle, *big.Int) {
+	group := NewSignedQRGroup(rsasetup.RSAMod)
+	return &Puzzle{N: puzzle.N, T: puzzle.T, Z: group.Exp(puzzle.Z, rsasetup.D)}, group.Element(vrf)
+}
+
+// PuzzleProve proves that the solution s hidden in the puzzle is committed in g^s and that s^D = GenVRF(message)^2 mod RSAMod.
+// pi2 is a Wesolowski proof of the puzzle raised to D, which the trapdoor computes with one exponentiation. As pi1 shows
+// that the committed s is the only D-th root of the VRF value, pi2 shows that the puzzle hides |s|.
+func PuzzleProve(pp *PublicParameters, message []byte, s *big.Int, puzzle *Puzzle, rsasetup *RSAExpProof) (*VTLPVRFProof, error) {
 	var ret VTLPVRFProof
+	if puzzle.Group != nil || puzzle.N.Cmp(rsasetup.RSAMod) != 0 ||
+		rsasetup.Solution(puzzle).Cmp(NewSignedQRGroup(rsasetup.RSAMod).Element(s)) != 0 {
+		return nil, errors.New("PuzzleProve inputs a puzzle not hiding s")
+	}
 
-	var C1, s2e, C2 big.Int
+	var s2e big.Int
 	// C = g^s, s^e mod N = Hash(m)
-	C1.Exp(pp.G, s, pp.N)
-	s2e.Exp(s, rsasetup.E, rsasetup.RSAMod)
-	if s2e.Cmp(GenVRF(message, rsasetup)) != 0 {
+	C1 := pp.expG(s)
+	s2e.Exp(s, rsasetup.D, rsasetup.RSAMod)
+	vrf := vrfValue(message, rsasetup.PublicPart())
+	if s2e.Cmp(vrf) != 0 {
 		return nil, errors.New("PuzzleProve inputs an invalid statement")
 	}
-	C2.Exp(pp.G, &s2e, pp.N)
-	tempProof1, err := ZKPoMoDEFastProve(pp, &C1, &C2, rsasetup.RSAMod, rsasetup.E, &s2e, s)
+	s2e.Exp(s, rsasetup.D, nil)
+	ret.C2 = pp.expG(&s2e)
+	transcript := vrfTranscript(pp, puzzle, C1, vrf, ret.C2, rsasetup.PublicPart())
+	tempProof1, err := zkpomodeFastProve(transcript.Fork("pi1"), pp, C1, ret.C2, rsasetup.RSAMod, rsasetup.D, vrf, s)
 	if err != nil {
 		return nil, err
 	}
 	ret.pi1 = tempProof1
 
+	derived, _ := vrfPuzzle(puzzle, vrf, rsasetup.PublicPart())
+	_, ret.pi2 = rsasetup.ProveSolution(derived)
+
 	return &ret, nil
 }
+
+// PuzzleVerify checks that the puzzle hides the VRF value of message committed in commitment = g^s, returns true if everything is good
+func PuzzleVerify(pp *PublicParameters, message []byte, puzzle *Puzzle, commitment *big.Int, rsasetup *RSAExpPublic, proof *VTLPVRFProof) bool {
+	return PuzzleVerifyErr(pp, message, puzzle, commitment, rsasetup, proof) == nil
+}
+
+// PuzzleVerifyErr checks that the puzzle hides the VRF value of message committed in commitment = g^s, returns nil if
+// everything is good and otherwise the reason of the failure, a failed sub-proof is reported as a *SubproofError
+func PuzzleVerifyErr(pp *PublicParameters, message []byte, puzzle *Puzzle, commitment *big.Int, rsasetup *RSAExpPublic, proof *VTLPVRFProof) error {
+	if err := proof.Validate(pp, puzzle, commitment, rsasetup); err != nil {
+		return err
+	}
+	vrf := vrfValue(message, rsasetup)
+	transcript := vrfTranscript(pp, puzzle, commitment, vrf, proof.C2, rsasetup)
+	if err := zkpomodeFastVerify(transcript.Fork("pi1"), pp, commitment, proof.C2, rsasetup.RSAMod, rsasetup.D, vrf, proof.pi1); err != nil {
+		return subproofFailed("VTLPVRF", "pi1", err)
+	}
+	derived, y := vrfPuzzle(puzzle, vrf, rsasetup)
+	return subproofFailed("VTLPVRF", "pi2", WesolowskiVerifyErr(derived, y, proof.pi2))
+}
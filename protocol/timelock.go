@@ -0,0 +1,104 @@
+package protocol
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+	"strconv"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// timeLockKeySize is the size of the AES-256-GCM key derived from the puzzle solution
+const timeLockKeySize = 32
+
+// TimeLockCiphertext contains a payload encrypted with AES-256-GCM under a key derived from the solution of Puzzle
+type TimeLockCiphertext struct {
+	Puzzle     *Puzzle
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+func (ciphertext *TimeLockCiphertext) isEmpty() bool {
+	if ciphertext.Puzzle == nil || ciphertext.Puzzle.N == nil || ciphertext.Puzzle.Z == nil || ciphertext.Nonce == nil || ciphertext.Ciphertext == nil {
+		return true
+	}
+	return false
+}
+
+// timeLockAEAD derives the key from the solution s with HKDF-SHA256, the puzzle is bound to the key through the info
+func timeLockAEAD(puzzle *Puzzle, s *big.Int) (cipher.AEAD, error) {
+	secret := make([]byte, (puzzle.N.BitLen()+7)/8)
+	s.FillBytes(secret)
+	info := []byte("VTLP time-lock " + puzzle.N.String() + " " + strconv.FormatInt(puzzle.T, 10) + " " + puzzle.Z.String())
+	key := make([]byte, timeLockKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, info), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts plaintext under a fresh puzzle with hardness T, the puzzle is generated with the trapdoor of rsasetup so that
+// sealing is fast while opening takes T sequential squarings. additionalData is authenticated but not encrypted.
+func Seal(rsasetup *RSAExpProof, T int64, plaintext, additionalData []byte) (*TimeLockCiphertext, error) {
+	r, err := rand.Int(rand.Reader, rsasetup.RSAMod)
+	if err != nil {
+		return nil, err
+	}
+	// s = r^2 is a random quadratic residue, the puzzle hides |s|
+	s := r.Exp(r, big2, rsasetup.RSAMod)
+	puzzle, err := rsasetup.NewPuzzle(s, T)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := timeLockAEAD(puzzle, NewSignedQRGroup(rsasetup.RSAMod).Element(s))
+	if err != nil {
+		return nil, err
+	}
+	ret := TimeLockCiphertext{Puzzle: puzzle, Nonce: make([]byte, aead.NonceSize())}
+	if _, err = io.ReadFull(rand.Reader, ret.Nonce); err != nil {
+		return nil, err
+	}
+	ret.Ciphertext = aead.Seal(nil, ret.Nonce, plaintext, additionalData)
+	return &ret, nil
+}
+
+// Open decrypts the ciphertext by solving its puzzle with SolvePuzzle, opts configures the solver and may be nil.
+// This function takes a long time to open!
+func Open(ctx context.Context, ciphertext *TimeLockCiphertext, additionalData []byte, opts *SolveOptions) ([]byte, error) {
+	if ciphertext == nil || ciphertext.isEmpty() {
+		return nil, errors.New("Open inputs an empty ciphertext")
+	}
+	s, err := SolvePuzzle(ctx, ciphertext.Puzzle, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ciphertext.OpenWithSolution(s, additionalData)
+}
+
+// OpenWithSolution decrypts the ciphertext given the solution s of its puzzle, e.g. computed by RSAExpProof.Solution
+func (ciphertext *TimeLockCiphertext) OpenWithSolution(s *big.Int, additionalData []byte) ([]byte, error) {
+	if ciphertext.isEmpty() {
+		return nil, errors.New("OpenWithSolution inputs an empty ciphertext")
+	}
+	if s == nil || s.Sign() < 0 || s.Cmp(ciphertext.Puzzle.N) >= 0 {
+		return nil, errors.New("OpenWithSolution inputs an invalid solution")
+	}
+	aead, err := timeLockAEAD(ciphertext.Puzzle, s)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext.Nonce) != aead.NonceSize() {
+		return nil, errors.New("TimeLockCiphertext has a wrong nonce size")
+	}
+	return aead.Open(nil, ciphertext.Nonce, ciphertext.Ciphertext, additionalData)
+}
@@ -0,0 +1,31 @@
+package protocol
+
+import (
+	"math/big"
+
+	fiatshamir "github.com/VTLP/fiat-shamir"
+)
+
+// newTranscript starts the Fiat-Shamir transcript of a proof in group. The protocol name separates the proofs from
+// each other and the binding of the group is appended before the statement. A sub-proof continues parent, the fork
+// of the transcript of the enclosing proof, so that its challenges are bound to the whole statement, a proof on its
+// own passes nil.
+func newTranscript(parent *fiatshamir.Transcript, protocol string, group Group) *fiatshamir.Transcript {
+	transcript := parent
+	if transcript == nil {
+		transcript = fiatshamir.NewTranscript(protocol, fiatshamir.Max252)
+	} else {
+		transcript.AppendMessage("dom-sep", []byte(protocol))
+	}
+	transcript.AppendMessage("group", group.Binding())
+	return transcript
+}
+
+// puzzleTranscript starts the transcript of a proof that y is the solution of puzzle
+func puzzleTranscript(protocol string, puzzle *Puzzle, y *big.Int) *fiatshamir.Transcript {
+	transcript := newTranscript(nil, protocol, puzzle.group())
+	transcript.AppendInts("T", big.NewInt(puzzle.T))
+	transcript.AppendInts("Z", puzzle.Z)
+	transcript.AppendInts("y", y)
+	return transcript
+}
@@ -0,0 +1,213 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// maxChallengeBits bounds the bit length of Max252 challenges, so that remainders modulo a challenge can be range
+// checked before the challenge is recomputed. Verifiers check the exact bound once they have the challenge.
+const maxChallengeBits = 252
+
+var (
+	// ErrMalformedProof is matched by errors of verifiers rejecting a statement or a proof before checking it, the
+	// error is a *ValidationError naming the element
+	ErrMalformedProof = errors.New("malformed proof")
+	// ErrChallengeMismatch is matched by errors of verifiers whose verification equation fails for the challenge
+	ErrChallengeMismatch = errors.New("challenge mismatch")
+	// ErrSubproofFailed is matched by errors of composite verifiers rejecting a sub-proof, the error is a *SubproofError
+	ErrSubproofFailed = errors.New("sub-proof failed")
+)
+
+// ValidationError reports which element of a statement or a proof failed the input validation of a verifier
+type ValidationError struct {
+	// Proof is the name of the proof, e.g. "ZKPoKE"
+	Proof string
+	// Element is the name of the statement or proof element, e.g. "rx"
+	Element string
+	// Reason explains why the element is invalid
+	Reason string
+}
+
+// Error returns the proof, the element and the reason
+func (err *ValidationError) Error() string {
+	return err.Proof + ": " + err.Element + " " + err.Reason
+}
+
+// Is makes a ValidationError match ErrMalformedProof
+func (err *ValidationError) Is(target error) bool {
+	return target == ErrMalformedProof
+}
+
+// SubproofError reports the sub-proof rejected by a composite verifier, Err is the error of the sub-proof verifier
+type SubproofError struct {
+	// Proof is the name of the composite proof, e.g. "ZKPoKDE"
+	Proof string
+	// Subproof is the name of the sub-proof in the composite proof, e.g. "pi3"
+	Subproof string
+	Err      error
+}
+
+// Error returns the proof, the sub-proof and the error of the sub-proof
+func (err *SubproofError) Error() string {
+	return err.Proof + ": sub-proof " + err.Subproof + " failed: " + err.Err.Error()
+}
+
+// Is makes a SubproofError match ErrSubproofFailed
+func (err *SubproofError) Is(target error) bool {
+	return target == ErrSubproofFailed
+}
+
+// Unwrap returns the error of the sub-proof verifier
+func (err *SubproofError) Unwrap() error {
+	return err.Err
+}
+
+// subproofFailed wraps the error of a sub-proof verifier, it returns nil if err is nil
+func subproofFailed(proof, subproof string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &SubproofError{Proof: proof, Subproof: subproof, Err: err}
+}
+
+// challengeMismatch returns the error of a failed verification equation
+func challengeMismatch(proof, equation string) error {
+	return fmt.Errorf("%s: %w: %s does not hold", proof, ErrChallengeMismatch, equation)
+}
+
+// malformed returns the error of an element which fails a check after the challenge is computed
+func malformed(proof, element, reason string) error {
+	return &ValidationError{Proof: proof, Element: element, Reason: reason}
+}
+
+// validator runs the checks of one proof and keeps the first failure, later checks are skipped
+type validator struct {
+	proof string
+	group Group
+	err   *ValidationError
+}
+
+// newValidator returns a validator of the proof in group
+func newValidator(proof string, group Group) *validator {
+	return &validator{proof: proof, group: group}
+}
+
+// parameters checks pp and switches the validator to the group of pp
+func (v *validator) parameters(pp *PublicParameters) {
+	if v.err != nil {
+		return
+	}
+	if pp == nil || pp.G == nil || (pp.Group == nil && pp.N == nil) {
+		v.fail("pp", "is missing")
+		return
+	}
+	v.group = pp.group()
+}
+
+// result returns the first failure, or nil if every check passed
+func (v *validator) result() error {
+	if v.err == nil {
+		return nil
+	}
+	return v.err
+}
+
+func (v *validator) fail(element, reason string) {
+	if v.err == nil {
+		v.err = &ValidationError{Proof: v.proof, Element: element, Reason: reason}
+	}
+}
+
+// present checks that a proof or sub-proof is not nil, it returns true if the checks so far passed
+func (v *validator) present(element string, ok bool) bool {
+	if !ok {
+		v.fail(element, "is missing")
+	}
+	return v.err == nil
+}
+
+// element checks that x is an element of the group
+func (v *validator) element(name string, x *big.Int) {
+	if v.err != nil {
+		return
+	}
+	if x == nil {
+		v.fail(name, "is missing")
+	} else if !v.group.IsElement(x) {
+		v.fail(name, "is not a group element")
+	}
+}
+
+// remainder checks that r is a non-negative integer of at most bits bits
+func (v *validator) remainder(name string, r *big.Int, bits int) {
+	if v.err != nil {
+		return
+	}
+	if r == nil {
+		v.fail(name, "is missing")
+	} else if r.Sign() < 0 {
+		v.fail(name, "is negative")
+	} else if r.BitLen() > bits {
+		v.fail(name, "is longer than "+strconv.Itoa(bits)+" bits")
+	}
+}
+
+// nonNegative checks that x is a non-negative integer
+func (v *validator) nonNegative(name string, x *big.Int) {
+	if v.err != nil {
+		return
+	}
+	if x == nil {
+		v.fail(name, "is missing")
+	} else if x.Sign() < 0 {
+		v.fail(name, "is negative")
+	}
+}
+
+// positive checks that x is a positive integer
+func (v *validator) positive(name string, x *big.Int) {
+	if v.err != nil {
+		return
+	}
+	if x == nil {
+		v.fail(name, "is missing")
+	} else if x.Sign() <= 0 {
+		v.fail(name, "is not positive")
+	}
+}
+
+// below checks that x is in [0, bound)
+func (v *validator) below(name string, x, bound *big.Int) {
+	if v.err != nil {
+		return
+	}
+	if x == nil {
+		v.fail(name, "is missing")
+	} else if x.Sign() < 0 || x.Cmp(bound) >= 0 {
+		v.fail(name, "is out of range")
+	}
+}
+
+// puzzle checks the puzzle and switches the validator to the group of the puzzle
+func (v *validator) puzzle(puzzle *Puzzle) {
+	if v.err != nil {
+		return
+	}
+	if puzzle == nil {
+		v.fail("puzzle", "is missing")
+		return
+	}
+	if puzzle.Group == nil && (puzzle.N == nil || puzzle.N.Sign() <= 0) {
+		v.fail("N", "is not a positive modulus")
+		return
+	}
+	if puzzle.T <= 0 {
+		v.fail("T", "is not positive")
+		return
+	}
+	v.group = puzzle.group()
+	v.element("Z", puzzle.Z)
+}
@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"encoding"
+	"math/big"
+)
+
+// VDFProof is a proof for the solution y = Z^{2^T} of a puzzle, WesolowskiProof and PietrzakProof implement it
+type VDFProof interface {
+	encoding.BinaryMarshaler
+}
+
+// VDF proves solutions of puzzles from the intermediate values kept by the solver, so that verifiers do not need to redo
+// the squarings. WesolowskiVDF has the shorter proof, PietrzakVDF the cheaper prover.
+type VDF interface {
+	// Prove proves y = Z^{2^T} given points[i] = Z^{2^{ik}} for every ik < T
+	Prove(puzzle *Puzzle, y *big.Int, points []*big.Int, k int64) (VDFProof, error)
+	// Verify checks the proof, returns true if everything is good
+	Verify(puzzle *Puzzle, y *big.Int, proof VDFProof) bool
+}
+
+// WesolowskiVDF implements VDF with WesolowskiProve and WesolowskiVerify
+type WesolowskiVDF struct{}
+
+// Prove implements VDF
+func (WesolowskiVDF) Prove(puzzle *Puzzle, y *big.Int, points []*big.Int, k int64) (VDFProof, error) {
+	proof, err := WesolowskiProve(puzzle, y, points, k)
+	if err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// Verify implements VDF
+func (WesolowskiVDF) Verify(puzzle *Puzzle, y *big.Int, proof VDFProof) bool {
+	p, ok := proof.(*WesolowskiProof)
+	return ok && WesolowskiVerify(puzzle, y, p)
+}
+
+// PietrzakVDF implements VDF with PietrzakProve and PietrzakVerify
+type PietrzakVDF struct{}
+
+// Prove implements VDF
+func (PietrzakVDF) Prove(puzzle *Puzzle, y *big.Int, points []*big.Int, k int64) (VDFProof, error) {
+	proof, err := PietrzakProve(puzzle, y, points, k)
+	if err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// Verify implements VDF
+func (PietrzakVDF) Verify(puzzle *Puzzle, y *big.Int, proof VDFProof) bool {
+	p, ok := proof.(*PietrzakProof)
+	return ok && PietrzakVerify(puzzle, y, p)
+}
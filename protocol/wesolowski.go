@@ -0,0 +1,144 @@
+package protocol
+
+import (
+	"errors"
+	"math/big"
+)
+
+const (
+	// maxProofPoints bounds the number of intermediate values Z^{2^{ik}} the solver keeps for the proof
+	maxProofPoints = 1 << 16
+	// maxProofWindow bounds the window size of the multi-exponentiation in WesolowskiProve
+	maxProofWindow = 16
+)
+
+// WesolowskiProof contains the proof for the solution y = Z^{2^T} of a puzzle, Pi = Z^{floor(2^T/l)}
+type WesolowskiProof struct {
+	Pi *big.Int
+}
+
+func (proof *WesolowskiProof) isEmpty() bool {
+	if proof.Pi == nil {
+		return true
+	}
+	return false
+}
+
+// proofInterval returns the number of squarings k between two intermediate values kept for the proof of a puzzle with hardness T
+func proofInterval(T int64) int64 {
+	k := (T + maxProofPoints - 1) / maxProofPoints
+	if k < 1 {
+		k = 1
+	}
+	return k
+}
+
+func wesolowskiChallenge(puzzle *Puzzle, y *big.Int) *big.Int {
+	return puzzleTranscript("Wesolowski", puzzle, y).PrimeChallenge("l")
+}
+
+// WesolowskiProve proves y = Z^{2^T} given points[i] = Z^{2^{ik}} for every ik < T, as kept by SolvePuzzleWithProof.
+// Writing floor(2^T/l) = sum_i b_i 2^{ik} with k-bit digits b_i, the proof is prod_i points[i]^{b_i}, which costs about T/w
+// multiplications for windows of w bits instead of T squarings.
+func WesolowskiProve(puzzle *Puzzle, y *big.Int, points []*big.Int, k int64) (*WesolowskiProof, error) {
+	if k <= 0 || int64(len(points)) != (puzzle.T+k-1)/k {
+		return nil, errors.New("WesolowskiProve inputs a wrong number of intermediate values")
+	}
+	l := wesolowskiChallenge(puzzle, y)
+
+	// b_i = floor(2^{T-ik}/l) mod 2^k = (2^{T-ik} mod l*2^k) / l
+	var mod, e big.Int
+	mod.Lsh(l, uint(k))
+	digits := make([]*big.Int, len(points))
+	for i := range points {
+		e.SetInt64(puzzle.T - int64(i)*k)
+		digits[i] = new(big.Int).Exp(big2, &e, &mod)
+		digits[i].Div(digits[i], l)
+	}
+
+	w := int64(big.NewInt(int64(len(points))).BitLen() - 4)
+	if w > maxProofWindow {
+		w = maxProofWindow
+	}
+	if w > k {
+		w = k
+	}
+	if w < 1 {
+		w = 1
+	}
+	group := puzzle.group()
+	ret := &WesolowskiProof{Pi: group.Identity()}
+	buckets := make([]*big.Int, 1<<w)
+	for top := ((k + w - 1) / w) * w; top > 0; top -= w {
+		for j := int64(0); j < w; j++ {
+			ret.Pi = group.Mul(ret.Pi, ret.Pi)
+		}
+		for v := range buckets {
+			buckets[v] = nil
+		}
+		for i, digit := range digits {
+			v := windowDigit(digit, top-w, w)
+			if v == 0 {
+				continue
+			}
+			if buckets[v] == nil {
+				buckets[v] = points[i]
+			} else {
+				buckets[v] = group.Mul(buckets[v], points[i])
+			}
+		}
+		// prod_v buckets[v]^v as a running product from the largest digit down
+		running, sum := group.Identity(), group.Identity()
+		for v := len(buckets) - 1; v > 0; v-- {
+			if buckets[v] != nil {
+				running = group.Mul(running, buckets[v])
+			}
+			sum = group.Mul(sum, running)
+		}
+		ret.Pi = group.Mul(ret.Pi, sum)
+	}
+	return ret, nil
+}
+
+// windowDigit returns the w bits of x starting at bit offset
+func windowDigit(x *big.Int, offset, w int64) int {
+	v := 0
+	for j := w - 1; j >= 0; j-- {
+		v = v<<1 | int(x.Bit(int(offset+j)))
+	}
+	return v
+}
+
+// Validate checks the puzzle and that y and Pi are elements of the group of the puzzle. RSA puzzles run in QR_N^+, so
+// y and Pi must be canonical |x|: in Z_N^* the proof -Pi for -y passes whenever l is odd.
+func (proof *WesolowskiProof) Validate(puzzle *Puzzle, y *big.Int) error {
+	v := newValidator("Wesolowski", nil)
+	v.puzzle(puzzle)
+	v.element("y", y)
+	if v.present("proof", proof != nil) {
+		v.element("Pi", proof.Pi)
+	}
+	return v.result()
+}
+
+// WesolowskiVerify checks the proof that y = Z^{2^T}, returns true if everything is good.
+// The verifier only computes r = 2^T mod l and checks Pi^l * Z^r = y.
+func WesolowskiVerify(puzzle *Puzzle, y *big.Int, proof *WesolowskiProof) bool {
+	return WesolowskiVerifyErr(puzzle, y, proof) == nil
+}
+
+// WesolowskiVerifyErr checks the proof that y = Z^{2^T}, returns nil if everything is good and otherwise the
+// reason of the failure
+func WesolowskiVerifyErr(puzzle *Puzzle, y *big.Int, proof *WesolowskiProof) error {
+	if err := proof.Validate(puzzle, y); err != nil {
+		return err
+	}
+	l := wesolowskiChallenge(puzzle, y)
+	var r big.Int
+	r.Exp(big2, big.NewInt(puzzle.T), l)
+	group := puzzle.group()
+	if !group.Equal(group.MultiExp([]*big.Int{proof.Pi, puzzle.Z}, []*big.Int{l, &r}), y) {
+		return challengeMismatch("Wesolowski", "Pi^l * Z^(2^T mod l) = y")
+	}
+	return nil
+}